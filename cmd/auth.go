@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect authentication state",
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the logged-in user, granted scopes and token expiry",
+	Long: `Show the logged-in user, granted scopes and token expiry.
+
+Exits non-zero when not logged in or when the token is expired and cannot be
+refreshed, so scripts can use it as a login check.`,
+	Annotations: rendersDocuments(),
+	RunE:        runAuthStatus,
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:         "whoami",
+	Short:       "Alias for `auth status`",
+	Annotations: rendersDocuments(),
+	RunE:        runAuthStatus,
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	sess, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	info, err := sess.client.UserInfo(cmd.Context())
+	if err != nil {
+		return err
+	}
+	token, err := sess.tokens.Token(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	doc := authStatusDoc{
+		User:       *info,
+		Host:       sess.cfg.APIBaseURL,
+		Profile:    sess.cfg.Profile,
+		TokenStore: sess.store.Location(),
+		Scopes:     token.Scope,
+	}
+	if doc.Scopes == nil {
+		doc.Scopes = []string{}
+	}
+	if !token.ExpiresAt.IsZero() {
+		doc.ExpiresAt = &token.ExpiresAt
+	}
+
+	return render(cmd, doc, func() error {
+		cmd.Printf("User: %s\n", describeUser(info))
+		cmd.Printf("Host: %s\n", doc.Host)
+		if doc.Profile != "" {
+			cmd.Printf("Profile: %s\n", doc.Profile)
+		}
+		cmd.Printf("Token store: %s\n", doc.TokenStore)
+		if len(doc.Scopes) > 0 {
+			cmd.Printf("Scopes: %s\n", strings.Join(doc.Scopes, " "))
+		} else {
+			cmd.Println("Scopes: <none>")
+		}
+		if doc.ExpiresAt == nil {
+			cmd.Println("Expires: never")
+		} else {
+			remaining := time.Until(*doc.ExpiresAt).Round(time.Second)
+			cmd.Printf("Expires: in %s (%s)\n", remaining, doc.ExpiresAt.Local().Format(time.RFC3339))
+		}
+		return nil
+	})
+}
+
+// authStatusDoc is the --output document of `auth status`.
+type authStatusDoc struct {
+	User       api.UserInfo `json:"user"`
+	Host       string       `json:"host"`
+	Profile    string       `json:"profile,omitempty"`
+	TokenStore string       `json:"token_store"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+}
+
+func describeUser(info *api.UserInfo) string {
+	name := info.PreferredUsername
+	if name == "" {
+		name = info.Subject
+	}
+	var details []string
+	if info.Name != "" && info.Name != name {
+		details = append(details, info.Name)
+	}
+	if info.Email != "" {
+		details = append(details, "<"+info.Email+">")
+	}
+	if len(details) == 0 {
+		return name
+	}
+	return name + " (" + strings.Join(details, " ") + ")"
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authStatusCmd)
+	rootCmd.AddCommand(whoamiCmd)
+}
@@ -12,8 +12,9 @@ var contextCmd = &cobra.Command{
 }
 
 var contextListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List available contexts",
+	Use:         "list",
+	Short:       "List available contexts",
+	Annotations: rendersDocuments(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		st := requireState()
 		names := make([]string, 0, len(st.Contexts))
@@ -22,22 +23,47 @@ var contextListCmd = &cobra.Command{
 		}
 		sort.Strings(names)
 
-		current := st.CurrentContext
+		docs := make([]contextDoc, 0, len(names))
 		for _, name := range names {
 			ctx := st.Contexts[name]
-			marker := " "
-			if name == current {
-				marker = "*"
-			}
-			cmd.Printf("%s %s (collection: %s, folder: %s)\n", marker, name, ctx.Collection, ctx.Folder)
-		}
-		if len(names) == 0 {
-			cmd.Println("No contexts defined. Run `codestash init --folder <id>` to create one.")
+			docs = append(docs, contextDoc{
+				Name:       name,
+				Collection: ctx.Collection,
+				Folder:     ctx.Folder,
+				Profile:    ctx.Profile,
+				Current:    name == st.CurrentContext,
+			})
 		}
-		return nil
+
+		return render(cmd, docs, func() error {
+			for _, doc := range docs {
+				marker := " "
+				if doc.Current {
+					marker = "*"
+				}
+				if doc.Profile != "" {
+					cmd.Printf("%s %s (collection: %s, folder: %s, profile: %s)\n", marker, doc.Name, doc.Collection, doc.Folder, doc.Profile)
+				} else {
+					cmd.Printf("%s %s (collection: %s, folder: %s)\n", marker, doc.Name, doc.Collection, doc.Folder)
+				}
+			}
+			if len(docs) == 0 {
+				cmd.Println("No contexts defined. Run `codestash init --folder <id>` to create one.")
+			}
+			return nil
+		})
 	},
 }
 
+// contextDoc is a context in --output documents.
+type contextDoc struct {
+	Name       string `json:"name"`
+	Collection string `json:"collection"`
+	Folder     string `json:"folder"`
+	Profile    string `json:"profile,omitempty"`
+	Current    bool   `json:"current"`
+}
+
 var contextSwitchCmd = &cobra.Command{
 	Use:   "switch <name>",
 	Short: "Switch active context",
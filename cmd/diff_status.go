@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var diffStatusCmd = &cobra.Command{
+	Use:   "diff-status",
+	Short: "Show which mapped files changed locally or remotely",
+	Long: `List every file mapped in the current context, like git status.
+
+Each file is compared with its note and with the content hash and remote
+revision recorded at the last push, pull or sync:
+
+  modified          changed locally since the last sync
+  remote changed    the note changed since the last sync
+  conflict          both changed
+  missing           the local file no longer exists
+  deleted remotely  the note no longer exists
+
+Nothing is changed; use ` + "`codestash sync`" + ` to reconcile.`,
+	SilenceUsage: true,
+	Annotations:  rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st := requireState()
+		ctx, err := st.Current()
+		if err != nil {
+			return err
+		}
+		paths := st.FileMappings(ctx.Name)
+		if len(paths) == 0 {
+			return render(cmd, []diffStatusDoc{}, func() error {
+				cmd.Println("No mapped files in this context.")
+				return nil
+			})
+		}
+
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+
+		clean := true
+		statuses := make([]mappingStatus, 0, len(paths))
+		docs := make([]diffStatusDoc, 0, len(paths))
+		for _, rel := range paths {
+			m, _ := st.GetFileMapping(ctx.Name, rel)
+			status, err := inspectMapping(cmd, sess, rel, m)
+			if err != nil {
+				return err
+			}
+			if status.state != stateUnchanged {
+				clean = false
+			}
+			statuses = append(statuses, status)
+			docs = append(docs, diffStatusDoc{Path: rel, NoteID: m.NoteID, State: strings.ReplaceAll(string(status.state), " ", "_")})
+		}
+
+		return render(cmd, docs, func() error {
+			for _, status := range statuses {
+				cmd.Printf("%-17s %s -> %s\n", status.state, status.path, status.mapping.NoteID)
+			}
+			if clean {
+				cmd.Println("All mapped files are in sync.")
+			}
+			return nil
+		})
+	},
+}
+
+// diffStatusDoc is a mapped file in the --output document of
+// `diff-status`. State is the one listed above, with _ for spaces.
+type diffStatusDoc struct {
+	Path   string `json:"path"`
+	NoteID string `json:"note_id"`
+	State  string `json:"state"`
+}
+
+func init() {
+	rootCmd.AddCommand(diffStatusCmd)
+}
@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/k-kanke/code-stash-cli/internal/config"
 )
 
 var (
@@ -30,13 +33,24 @@ var initCmd = &cobra.Command{
 			ctxName = "default"
 		}
 
-		st.SetContext(ctxName, initCollectionID, initFolderID)
+		// Only pin a profile the user asked for explicitly.
+		profile := strings.TrimSpace(viper.GetString("profile"))
+		if profile != "" {
+			if err := config.ValidateProfileName(profile); err != nil {
+				return err
+			}
+		}
+		st.SetContext(ctxName, initCollectionID, initFolderID, profile)
 		st.CurrentContext = ctxName
 		if err := st.Save(); err != nil {
 			return err
 		}
 
-		cmd.Printf("Initialized context %q with folder %s\n", ctxName, initFolderID)
+		if profile != "" {
+			cmd.Printf("Initialized context %q with folder %s (profile: %s)\n", ctxName, initFolderID, profile)
+		} else {
+			cmd.Printf("Initialized context %q with folder %s\n", ctxName, initFolderID)
+		}
 		return nil
 	},
 }
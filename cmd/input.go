@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxInputSize caps code and note bodies read from files or stdin.
+const maxInputSize = 1 << 20
+
+// stdinPath is the --file/--note value that reads from stdin.
+const stdinPath = "-"
+
+// readInput reads the file at path, or stdin when path is "-". what names
+// the input in errors.
+func readInput(cmd *cobra.Command, path, what string) ([]byte, error) {
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", what, err)
+		}
+		defer f.Close()
+		return readLimited(f, what)
+	}
+
+	in := cmd.InOrStdin()
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		return nil, fmt.Errorf("%s is read from stdin, but stdin is a terminal; pipe data in or pass a file path", what)
+	}
+	data, err := readLimited(in, what)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s: no data on stdin", what)
+	}
+	return data, nil
+}
+
+func readLimited(r io.Reader, what string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", what, err)
+	}
+	if len(data) > maxInputSize {
+		return nil, fmt.Errorf("%s is larger than %d KiB", what, maxInputSize>>10)
+	}
+	return data, nil
+}
+
+// checkStdinOnce rejects reading more than one input from stdin.
+func checkStdinOnce(paths ...string) error {
+	n := 0
+	for _, p := range paths {
+		if p == stdinPath {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("only one of --file and --note can read from stdin")
+	}
+	return nil
+}
+
+// checkCodeSource requires code from either --file or --code.
+func checkCodeSource(cmd *cobra.Command, file string) error {
+	if strings.TrimSpace(file) == "" && !cmd.Flags().Changed("code") {
+		return errors.New("--file or --code is required")
+	}
+	return nil
+}
+
+// readCode returns the code from --file or --code, and the absolute path of
+// the file it came from; the path is empty for stdin and inline code.
+func readCode(cmd *cobra.Command, file, code string) ([]byte, string, error) {
+	if cmd.Flags().Changed("code") {
+		if len(code) > maxInputSize {
+			return nil, "", fmt.Errorf("code is larger than %d KiB", maxInputSize>>10)
+		}
+		return []byte(code), "", nil
+	}
+	if file == stdinPath {
+		data, err := readInput(cmd, file, "code")
+		return data, "", err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := readInput(cmd, absFile, "file")
+	return data, absFile, err
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -13,91 +14,143 @@ import (
 	"github.com/k-kanke/code-stash-cli/internal/config"
 )
 
+var (
+	loginWeb       bool
+	loginWithToken bool
+)
+
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Authenticate with CodeStash via device authorization",
+	Short: "Authenticate with CodeStash via device authorization or the browser",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load()
+		cfg, err := loadConfig()
 		if err != nil {
 			return err
 		}
 
-		client, err := api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret)
+		client, err := newClient(cfg)
+		if err != nil {
+			return err
+		}
+		store, err := openTokenStore(cfg)
 		if err != nil {
 			return err
 		}
 
-		ctx := cmd.Context()
-		deviceResp, err := client.StartDeviceCode(ctx)
+		if loginWithToken {
+			return loginWithPAT(cmd, cfg, store)
+		}
+
+		var tokenResp *api.TokenResponse
+		if loginWeb {
+			tokenResp, err = webLogin(cmd, client)
+			if errors.Is(err, errNoBrowser) {
+				cmd.Println("No browser available, falling back to device authorization.")
+				tokenResp, err = deviceLogin(cmd, client)
+			}
+		} else {
+			tokenResp, err = deviceLogin(cmd, client)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to request device code: %w", err)
+			return err
 		}
 
-		printInstructions(cmd, deviceResp)
+		if err := store.Save(tokenFromResponse(tokenResp)); err != nil {
+			return fmt.Errorf("save token: %w", err)
+		}
 
-		interval := time.Duration(deviceResp.Interval) * time.Second
-		if interval <= 0 {
-			interval = 5 * time.Second
+		cmd.Printf("\nLogin successful! Token saved to %s\n", store.Location())
+		return nil
+	},
+}
+
+// loginWithPAT reads a personal access token from stdin, checks it against
+// the API and stores it.
+func loginWithPAT(cmd *cobra.Command, cfg *config.Config, store auth.Store) error {
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("read token from stdin: %w", err)
+	}
+	pat := strings.TrimSpace(string(data))
+	if pat == "" {
+		return errors.New("no token on stdin; pipe one in, e.g. `echo \"$PAT\" | codestash login --with-token`")
+	}
+
+	client, err := newClient(cfg, api.WithTokenSource(api.StaticToken(pat)))
+	if err != nil {
+		return err
+	}
+	info, err := client.UserInfo(cmd.Context())
+	if err != nil {
+		if api.IsUnauthorized(err) {
+			return errors.New("the token was rejected by the server")
 		}
-		expiration := time.Now().Add(time.Duration(deviceResp.ExpiresIn) * time.Second)
+		return fmt.Errorf("validate token: %w", err)
+	}
 
-		for {
-			if time.Now().After(expiration) {
-				return errors.New("device code expired, please retry login")
-			}
+	if err := store.Save(auth.Token{AccessToken: pat}); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	cmd.Printf("Logged in as %s. Token saved to %s\n", describeUser(info), store.Location())
+	return nil
+}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(interval):
-			}
+func deviceLogin(cmd *cobra.Command, client *api.Client) (*api.TokenResponse, error) {
+	ctx := cmd.Context()
+	deviceResp, err := client.StartDeviceCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request device code: %w", err)
+	}
 
-			tokenResp, apiErr, err := client.ExchangeDeviceCode(ctx, deviceResp.DeviceCode)
-			if err != nil {
-				return fmt.Errorf("token exchange failed: %w", err)
-			}
-			if apiErr != nil {
-				switch apiErr.Code {
-				case "authorization_pending":
-					cmd.Print(".")
-					continue
-				case "slow_down":
-					interval += time.Second
-					cmd.Printf("\nServer asked to slow down, next attempt in %s\n", interval)
-					continue
-				case "expired_token":
-					return errors.New("device code expired, run login again")
-				case "access_denied":
-					return errors.New("authorization denied in the browser")
-				default:
-					return fmt.Errorf("token exchange error: %s", apiErr.Code)
-				}
-			}
+	printInstructions(cmd, deviceResp)
 
-			scope := strings.Fields(tokenResp.Scope)
-			expiresAt := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
-			var refresh string
-			if tokenResp.RefreshToken != nil {
-				refresh = *tokenResp.RefreshToken
-			}
+	interval := time.Duration(deviceResp.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	expiration := time.Now().Add(time.Duration(deviceResp.ExpiresIn) * time.Second)
 
-			if err := auth.SaveToken(cfg.TokenPath, auth.Token{
-				AccessToken:  tokenResp.AccessToken,
-				RefreshToken: refresh,
-				Scope:        scope,
-				ExpiresAt:    expiresAt,
-			}); err != nil {
-				return fmt.Errorf("save token: %w", err)
-			}
+	for {
+		if time.Now().After(expiration) {
+			return nil, errors.New("device code expired, please retry login")
+		}
 
-			cmd.Printf("\nLogin successful! Token saved to %s\n", cfg.TokenPath)
-			return nil
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
 		}
-	},
+
+		tokenResp, apiErr, err := client.ExchangeDeviceCode(ctx, deviceResp.DeviceCode)
+		if err != nil {
+			return nil, fmt.Errorf("token exchange failed: %w", err)
+		}
+		if apiErr != nil {
+			switch apiErr.Code {
+			case "authorization_pending":
+				cmd.Print(".")
+				continue
+			case "slow_down":
+				interval += time.Second
+				cmd.Printf("\nServer asked to slow down, next attempt in %s\n", interval)
+				continue
+			case "expired_token":
+				return nil, errors.New("device code expired, run login again")
+			case "access_denied":
+				return nil, errors.New("authorization denied in the browser")
+			default:
+				return nil, fmt.Errorf("token exchange failed: %w", apiErr)
+			}
+		}
+		return tokenResp, nil
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().BoolVar(&loginWeb, "web", false, "log in through the browser (authorization code + PKCE)")
+	loginCmd.Flags().BoolVar(&loginWithToken, "with-token", false, "read a personal access token from stdin")
+	loginCmd.MarkFlagsMutuallyExclusive("web", "with-token")
 }
 
 func printInstructions(cmd *cobra.Command, resp *api.DeviceCodeResponse) {
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+)
+
+// webLoginTimeout bounds how long we wait for the browser redirect.
+const webLoginTimeout = 5 * time.Minute
+
+// errNoBrowser means the browser flow cannot run here and the caller should
+// fall back to device authorization.
+var errNoBrowser = errors.New("no browser available")
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// webLogin runs the authorization-code flow with PKCE, receiving the code on
+// a loopback redirect (RFC 8252).
+func webLogin(cmd *cobra.Command, client *api.Client) (*api.TokenResponse, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("start loopback listener: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		// Anything on this machine can reach the port. Requests without our
+		// state are not the authorization response, so they must not end
+		// the login; keep waiting for the real one.
+		if q.Get("state") != state {
+			http.Error(w, "authorization response has an unexpected state", http.StatusBadRequest)
+			return
+		}
+		var res callbackResult
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", q.Get("error"))
+		case q.Get("code") == "":
+			res.err = errors.New("authorization response has no code")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "CodeStash CLI is authorized. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	authURL := client.AuthorizeURL(redirectURI, state, challenge)
+	if err := openBrowser(authURL); err != nil {
+		return nil, err
+	}
+	cmd.Println("Opened your browser to authorize this CLI. If it did not open, visit:")
+	cmd.Println(authURL)
+	cmd.Println("Waiting for authorization...")
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), webLoginTimeout)
+	defer cancel()
+
+	var res callbackResult
+	select {
+	case <-ctx.Done():
+		return nil, errors.New("timed out waiting for browser authorization, please retry login")
+	case res = <-results:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	tokenResp, apiErr, err := client.ExchangeAuthorizationCode(ctx, res.code, redirectURI, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if apiErr != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", apiErr)
+	}
+	return tokenResp, nil
+}
+
+func openBrowser(target string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return errNoBrowser
+		}
+		name = "xdg-open"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return errNoBrowser
+	}
+	if err := exec.Command(path, append(args, target)...).Start(); err != nil {
+		return errNoBrowser
+	}
+	return nil
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke the stored token and remove it from this machine",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		store, err := openTokenStore(cfg)
+		if err != nil {
+			return err
+		}
+		token, err := store.Load()
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			cmd.Println("Not logged in.")
+			return nil
+		}
+
+		client, err := newClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		// Revoking the refresh token also invalidates the access tokens issued
+		// from it on most servers; revoke both to be safe, even if one fails.
+		var revokeErrs []string
+		if strings.TrimSpace(token.RefreshToken) != "" {
+			if err := client.RevokeToken(cmd.Context(), token.RefreshToken, "refresh_token"); err != nil {
+				revokeErrs = append(revokeErrs, fmt.Sprintf("refresh token: %v", err))
+			}
+		}
+		if strings.TrimSpace(token.AccessToken) != "" {
+			if err := client.RevokeToken(cmd.Context(), token.AccessToken, "access_token"); err != nil {
+				revokeErrs = append(revokeErrs, fmt.Sprintf("access token: %v", err))
+			}
+		}
+
+		if err := store.Delete(); err != nil {
+			return err
+		}
+
+		if len(revokeErrs) > 0 {
+			cmd.Printf("Removed local token from %s, but server revocation failed:\n", store.Location())
+			for _, e := range revokeErrs {
+				cmd.Printf("  %s\n", e)
+			}
+			return nil
+		}
+		cmd.Printf("Logged out. Token revoked on %s and removed from %s\n", cfg.APIBaseURL, store.Location())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+type mappingState string
+
+const (
+	stateUnchanged     mappingState = "unchanged"
+	stateModified      mappingState = "modified"
+	stateRemoteChanged mappingState = "remote changed"
+	stateConflict      mappingState = "conflict"
+	stateMissing       mappingState = "missing"
+	stateDeleted       mappingState = "deleted remotely"
+)
+
+// mappingStatus compares a mapped file with its note and the last sync.
+type mappingStatus struct {
+	path    string
+	mapping state.FileMapping
+	state   mappingState
+	// local is nil when the file is missing; note is nil when it was deleted.
+	local []byte
+	note  *api.Note
+}
+
+func inspectMapping(cmd *cobra.Command, sess *session, rel string, m state.FileMapping) (mappingStatus, error) {
+	status := mappingStatus{path: rel, mapping: m}
+	abs := filepath.Join(projectRoot, filepath.FromSlash(rel))
+
+	info, err := os.Stat(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		status.state = stateMissing
+		return status, nil
+	case err != nil:
+		return status, err
+	}
+	status.local, err = os.ReadFile(abs)
+	if err != nil {
+		return status, fmt.Errorf("read %s: %w", rel, err)
+	}
+
+	status.note, err = sess.client.GetNote(cmd.Context(), m.NoteID)
+	if api.IsNotFound(err) {
+		status.state = stateDeleted
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	if string(status.local) == status.note.Code {
+		status.state = stateUnchanged
+		return status, nil
+	}
+	localChanged := m.LocalChanged(status.local, info.ModTime())
+	remoteChanged := m.RemoteChanged(status.note.UpdatedAt, status.note.Revision)
+	switch {
+	case localChanged && !remoteChanged:
+		status.state = stateModified
+	case remoteChanged && !localChanged:
+		status.state = stateRemoteChanged
+	default:
+		status.state = stateConflict
+	}
+	return status, nil
+}
+
+// recordSynced maps rel to noteID as synced with content and keeps content
+// as the base for later merges. updated is the note as returned by a write,
+// if the server sent one; otherwise it is fetched. The note is returned.
+func recordSynced(cmd *cobra.Command, sess *session, st *state.State, ctxName, rel, noteID string, content []byte, updated *api.Note) (*api.Note, error) {
+	if updated == nil {
+		var err error
+		updated, err = sess.client.GetNote(cmd.Context(), noteID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := st.SaveBase(content); err != nil {
+		return nil, err
+	}
+	st.SetFileMapping(ctxName, rel, state.SyncedMapping(noteID, content, updated.UpdatedAt, updated.Revision))
+	return updated, nil
+}
+
+// mappingPrecondition makes a write succeed only if the note is still the
+// version last synced to the file.
+func mappingPrecondition(m state.FileMapping) api.Precondition {
+	return api.Precondition{Revision: m.RemoteRevision, UpdatedAt: m.RemoteUpdatedAt}
+}
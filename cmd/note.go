@@ -8,8 +8,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/k-kanke/code-stash-cli/internal/api"
-	"github.com/k-kanke/code-stash-cli/internal/auth"
-	"github.com/k-kanke/code-stash-cli/internal/config"
 	"github.com/k-kanke/code-stash-cli/internal/state"
 )
 
@@ -37,32 +35,19 @@ var noteSwitchCmd = &cobra.Command{
 			// ok, switching into note scope
 		}
 
-		cfg, err := config.Load()
-		if err != nil {
-			return err
-		}
-		token, err := auth.LoadToken(cfg.TokenPath)
-		if err != nil {
-			return err
-		}
-		if token == nil {
-			return errors.New("not logged in; run `codestash login` first")
-		}
-
-		client, err := api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret)
+		sess, err := newSession()
 		if err != nil {
 			return err
 		}
 
-		notes, err := client.ListNotes(cmd.Context(), token.AccessToken, ctx.Collection)
+		notes, err := sess.client.ListNotes(cmd.Context(), ctx.Collection, api.NoteFilter{FolderID: ctx.Folder})
 		if err != nil {
 			return err
 		}
-		filtered := filterNotesByFolder(notes, ctx.Folder)
 
 		var selected *api.NoteSummary
-		for i := range filtered {
-			n := filtered[i]
+		for i := range notes {
+			n := notes[i]
 			if n.ID == noteID {
 				selected = &n
 				break
@@ -72,7 +57,7 @@ var noteSwitchCmd = &cobra.Command{
 			return fmt.Errorf("note %s not found in current folder", noteID)
 		}
 
-		if err := st.EnterNoteScope(selected.ID, selected.Title); err != nil {
+		if err := st.EnterNoteScope(selected.ID, selected.Title, selected.UpdatedAt); err != nil {
 			return err
 		}
 		if err := st.Save(); err != nil {
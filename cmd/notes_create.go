@@ -2,16 +2,12 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/k-kanke/code-stash-cli/internal/api"
-	"github.com/k-kanke/code-stash-cli/internal/auth"
-	"github.com/k-kanke/code-stash-cli/internal/config"
+	"github.com/k-kanke/code-stash-cli/internal/lang"
 )
 
 var (
@@ -20,101 +16,138 @@ var (
 	noteCreateTags     []string
 	noteCreateFile     string
 	noteCreateNoteFile string
+	noteCreateCode     string
 )
 
 var notesCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new note in the current context",
+	Long: `Create a new note in the current context.
+
+The code comes from --file, from stdin with --file -, or inline with --code.
+The note body can likewise come from --note <path> or --note -. A code file
+is mapped to the new note so that later updates and syncs find it.
+
+Without --language the language is detected from a vim/emacs modeline, the
+file extension or a shebang line. Without --title the first comment at the
+top of the code is used, or else the file name.`,
+	Annotations: rendersDocuments(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := requireFolderScope(); err != nil {
 			return err
 		}
-		if strings.TrimSpace(noteCreateFile) == "" {
-			return errors.New("--file is required")
+		if err := checkCodeSource(cmd, noteCreateFile); err != nil {
+			return err
 		}
-		if strings.TrimSpace(noteCreateTitle) == "" {
-			return errors.New("--title is required")
+		if err := checkStdinOnce(noteCreateFile, noteCreateNoteFile); err != nil {
+			return err
 		}
-
 		st := requireState()
 		ctx, err := st.Current()
 		if err != nil {
 			return err
 		}
 
-		cfg, err := config.Load()
+		sess, err := newSession()
 		if err != nil {
 			return err
 		}
 
-		httpClient, err := api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret)
+		fileContent, absFile, err := readCode(cmd, noteCreateFile, noteCreateCode)
 		if err != nil {
 			return err
 		}
 
-		absFile, err := filepath.Abs(noteCreateFile)
-		if err != nil {
-			return err
-		}
-		fileContent, err := os.ReadFile(absFile)
-		if err != nil {
-			return fmt.Errorf("read file: %w", err)
-		}
-
 		var noteContent string
 		if strings.TrimSpace(noteCreateNoteFile) != "" {
-			noteAbs, err := filepath.Abs(noteCreateNoteFile)
+			body, err := readInput(cmd, noteCreateNoteFile, "note body")
 			if err != nil {
 				return err
 			}
-			body, err := os.ReadFile(noteAbs)
-			if err != nil {
-				return fmt.Errorf("read note body: %w", err)
-			}
 			noteContent = string(body)
 		}
 
-		token, err := auth.LoadToken(cfg.TokenPath)
-		if err != nil {
-			return err
+		language := strings.TrimSpace(noteCreateLanguage)
+		if language == "" {
+			var from lang.Source
+			if language, from = lang.Detect(absFile, fileContent); language != "" {
+				printText(cmd, "Language: %s (detected from %s; use --language to override)\n", language, from)
+			}
 		}
-		if token == nil {
-			return errors.New("not logged in; run `codestash login` first")
+		title := strings.TrimSpace(noteCreateTitle)
+		if title == "" {
+			if title = lang.Title(absFile, fileContent, language); title == "" {
+				return errors.New("--title is required when it cannot be inferred from a file name or leading comment")
+			}
+			printText(cmd, "Title: %s (inferred; use --title to override)\n", title)
 		}
 
-		resp, err := httpClient.CreateNote(cmd.Context(), token.AccessToken, api.CreateNoteRequest{
+		payload := api.CreateNoteRequest{
 			CollectionID: ctx.Collection,
 			FolderID:     ctx.Folder,
-			Title:        noteCreateTitle,
-			Language:     noteCreateLanguage,
+			Title:        title,
+			Language:     language,
 			Tags:         noteCreateTags,
 			Code:         string(fileContent),
 			Note:         noteContent,
-		})
+		}
+		resp, err := sess.client.CreateNote(cmd.Context(), payload)
 		if err != nil {
 			return err
 		}
-		if resp == nil || resp.NoteID == "" {
-			cmd.Println("Note created, but the server did not return an ID. Skipping local mapping.")
+		doc := createdNoteDoc{Title: title, Language: language}
+		if resp != nil {
+			doc.ID = resp.NoteID
+		}
+		if doc.ID != "" && absFile != "" {
+			doc.File = relativeToRoot(absFile)
+		}
+		err = render(cmd, doc, func() error {
+			if doc.ID == "" {
+				cmd.Println("Note created, but the server did not return an ID. Skipping local mapping.")
+				return nil
+			}
+			cmd.Printf("Created note %q (ID: %s)\n", title, doc.ID)
 			return nil
+		})
+		if err != nil || doc.File == "" {
+			return err
 		}
 
-		st.SetFileMapping(ctx.Name, relativeToRoot(absFile), resp.NoteID)
-		if err := st.Save(); err != nil {
+		// The create response carries only the ID; fetch the note for the
+		// revision to make later updates conditional on. The note exists
+		// either way, so map the file even without it: failing here would
+		// invite a retry that creates a duplicate.
+		note, err := sess.client.GetNote(cmd.Context(), doc.ID)
+		if err != nil {
+			cmd.PrintErrf("Could not fetch the note's revision; mapping %s without it: %v\n", doc.File, err)
+			note = &api.Note{}
+		}
+		if _, err := recordSynced(cmd, sess, st, ctx.Name, doc.File, doc.ID, fileContent, note); err != nil {
 			return err
 		}
-
-		cmd.Printf("Created note %q (ID: %s)\n", noteCreateTitle, resp.NoteID)
-		return nil
+		return st.Save()
 	},
 }
 
+// createdNoteDoc is the --output document of `notes create`. ID is empty
+// if the server did not return one.
+type createdNoteDoc struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Language string `json:"language,omitempty"`
+	// File is the file mapped to the new note, if any.
+	File string `json:"file,omitempty"`
+}
+
 func init() {
 	notesCmd.AddCommand(notesCreateCmd)
 
-	notesCreateCmd.Flags().StringVar(&noteCreateTitle, "title", "", "note title")
-	notesCreateCmd.Flags().StringVar(&noteCreateLanguage, "language", "", "code language")
+	notesCreateCmd.Flags().StringVar(&noteCreateTitle, "title", "", "note title (default: inferred from the code or file name)")
+	notesCreateCmd.Flags().StringVar(&noteCreateLanguage, "language", "", "code language (default: detected)")
 	notesCreateCmd.Flags().StringSliceVar(&noteCreateTags, "tags", nil, "comma-separated tags")
-	notesCreateCmd.Flags().StringVar(&noteCreateFile, "file", "", "path to code file")
-	notesCreateCmd.Flags().StringVar(&noteCreateNoteFile, "note", "", "path to note/description file")
+	notesCreateCmd.Flags().StringVar(&noteCreateFile, "file", "", "path to code file, or - for stdin")
+	notesCreateCmd.Flags().StringVar(&noteCreateCode, "code", "", "code given inline instead of --file")
+	notesCreateCmd.Flags().StringVar(&noteCreateNoteFile, "note", "", "path to note/description file, or - for stdin")
+	notesCreateCmd.MarkFlagsMutuallyExclusive("file", "code")
 }
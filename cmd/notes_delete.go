@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+var noteDeleteYes bool
+
+var notesDeleteCmd = &cobra.Command{
+	Use:   "delete <note-id>...",
+	Short: "Delete notes",
+	Long: `Delete one or more notes after confirming.
+
+Local files are kept, but their mappings to the deleted notes are removed.
+If the current note is deleted, note scope is left. Use --yes to skip the
+confirmation, which is required when not running in a terminal.`,
+	Args:         cobra.MinimumNArgs(1),
+	Annotations:  rendersDocuments(),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]string, 0, len(args))
+		for _, arg := range args {
+			if id := strings.TrimSpace(arg); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return errors.New("note id is required")
+		}
+
+		if !noteDeleteYes {
+			if !isTerminal(os.Stdin) {
+				return errors.New("refusing to delete without confirmation; pass --yes")
+			}
+			if !confirm(cmd, fmt.Sprintf("Delete %d note(s): %s?", len(ids), strings.Join(ids, ", "))) {
+				return errors.New("delete aborted")
+			}
+		}
+
+		st := requireState()
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		docs := make([]deletedNoteDoc, 0, len(ids))
+		for _, id := range ids {
+			doc := deletedNoteDoc{ID: id, Result: "deleted"}
+			err := sess.client.DeleteNote(cmd.Context(), id)
+			switch {
+			case api.IsNotFound(err):
+				doc.Result = "not_found"
+				printText(cmd, "Note %s was already deleted\n", id)
+			case err != nil:
+				cmd.PrintErrf("Could not delete note %s: %v\n", id, err)
+				failed++
+				doc.Result, doc.Error = "failed", err.Error()
+				docs = append(docs, doc)
+				continue
+			default:
+				printText(cmd, "Deleted note %s\n", id)
+			}
+
+			if doc.RemovedMappings = st.RemoveNoteMappings(id); doc.RemovedMappings > 0 {
+				printText(cmd, "  removed %d file mapping(s)\n", doc.RemovedMappings)
+			}
+			if st.Scope() == state.ScopeNote && st.CurrentNoteID == id {
+				st.EnterFolderScope()
+				doc.LeftNoteScope = true
+				printText(cmd, "  left note scope\n")
+			}
+			docs = append(docs, doc)
+		}
+
+		if err := st.Save(); err != nil {
+			return err
+		}
+		// The text output was printed note by note above.
+		if err := render(cmd, docs, func() error { return nil }); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d note(s) could not be deleted", failed, len(ids))
+		}
+		return nil
+	},
+}
+
+// deletedNoteDoc is a note in the --output document of `notes delete`.
+type deletedNoteDoc struct {
+	ID string `json:"id"`
+	// Result is deleted, not_found if the note was already gone, or failed.
+	Result          string `json:"result"`
+	Error           string `json:"error,omitempty"`
+	RemovedMappings int    `json:"removed_mappings"`
+	LeftNoteScope   bool   `json:"left_note_scope"`
+}
+
+func init() {
+	notesCmd.AddCommand(notesDeleteCmd)
+
+	notesDeleteCmd.Flags().BoolVarP(&noteDeleteYes, "yes", "y", false, "delete without asking for confirmation")
+}
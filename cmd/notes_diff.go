@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/diff"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+var (
+	noteDiffFile    string
+	noteDiffContext int
+)
+
+var notesDiffCmd = &cobra.Command{
+	Use:   "diff [note-id]",
+	Short: "Show how a local file differs from its note",
+	Long: `Show a unified diff from a note's code to a local file, i.e. what
+` + "`notes update`" + ` would change.
+
+Without --file the file mapped to the note is used; without an ID the note
+mapped to --file is used, falling back to the current note scope.
+
+Like diff(1), exits 0 when there are no differences, 1 when there are and
+2 on errors.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if noteDiffContext < 0 {
+			return &exitError{code: 2, err: errors.New("--unified must not be negative")}
+		}
+		differs, err := runNotesDiff(cmd, args)
+		if err != nil {
+			return &exitError{code: 2, err: err}
+		}
+		if differs {
+			return exitStatus(cmd, 1)
+		}
+		return nil
+	},
+}
+
+func runNotesDiff(cmd *cobra.Command, args []string) (bool, error) {
+	st := requireState()
+	ctx, err := st.Current()
+	if err != nil {
+		return false, err
+	}
+	noteID, absFile, err := resolveDiffTarget(st, ctx.Name, args)
+	if err != nil {
+		return false, err
+	}
+
+	local, err := os.ReadFile(absFile)
+	if err != nil {
+		return false, fmt.Errorf("read file: %w", err)
+	}
+
+	sess, err := newSession()
+	if err != nil {
+		return false, err
+	}
+	note, err := sess.client.GetNote(cmd.Context(), noteID)
+	if err != nil {
+		return false, err
+	}
+
+	out := diff.Unified(note.Code, string(local), "note/"+noteID, relativeToRoot(absFile), noteDiffContext)
+	if out == "" {
+		return false, nil
+	}
+	if useColor(cmd.OutOrStdout()) {
+		out = colorizeDiff(out)
+	}
+	cmd.Print(out)
+	return true, nil
+}
+
+// resolveDiffTarget pairs a note with a local file, filling in whichever of
+// the two was not given from the file mappings.
+func resolveDiffTarget(st *state.State, ctxName string, args []string) (noteID, absFile string, err error) {
+	file := strings.TrimSpace(noteDiffFile)
+	if file != "" {
+		if absFile, err = filepath.Abs(file); err != nil {
+			return "", "", err
+		}
+	}
+
+	switch {
+	case len(args) > 0:
+		noteID = strings.TrimSpace(args[0])
+	case file != "":
+		if m, ok := st.GetFileMapping(ctxName, relativeToRoot(absFile)); ok {
+			noteID = m.NoteID
+		}
+	}
+	if noteID == "" {
+		if noteID, err = resolveNoteID(nil); err != nil {
+			return "", "", err
+		}
+	}
+
+	if absFile == "" {
+		rel, ok := st.FileForNote(ctxName, noteID)
+		if !ok {
+			return "", "", fmt.Errorf("note %s is not mapped to a file; use --file", noteID)
+		}
+		absFile = filepath.Join(projectRoot, filepath.FromSlash(rel))
+	}
+	return noteID, absFile, nil
+}
+
+// colorizeDiff colors a unified diff the way git does. Every line of a
+// diff ends in a newline.
+func colorizeDiff(s string) string {
+	const (
+		bold  = "\x1b[1m"
+		cyan  = "\x1b[36m"
+		red   = "\x1b[31m"
+		green = "\x1b[32m"
+		reset = "\x1b[0m"
+	)
+	var b strings.Builder
+	for i, line := range diff.Lines(s) {
+		color := ""
+		switch {
+		case i < 2:
+			color = bold
+		case strings.HasPrefix(line, "@@"):
+			color = cyan
+		case strings.HasPrefix(line, "-"):
+			color = red
+		case strings.HasPrefix(line, "+"):
+			color = green
+		}
+		if color == "" {
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString(color + strings.TrimSuffix(line, "\n") + reset + "\n")
+	}
+	return b.String()
+}
+
+func init() {
+	notesCmd.AddCommand(notesDiffCmd)
+
+	notesDiffCmd.Flags().StringVar(&noteDiffFile, "file", "", "local file to compare (default: the file mapped to the note)")
+	notesDiffCmd.Flags().IntVarP(&noteDiffContext, "unified", "U", 3, "lines of context around each change")
+}
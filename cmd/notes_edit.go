@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/lang"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+var notesEditCmd = &cobra.Command{
+	Use:   "edit [note-id]",
+	Short: "Edit a note's code and description in $EDITOR",
+	Long: `Open a note's code and description in your editor and upload what changed.
+
+The editor is taken from $VISUAL, then $EDITOR, and defaults to vi. Nothing
+is uploaded if the files are left unchanged or the editor exits with an
+error. Without an ID the note of the current note scope is edited.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noteID, err := resolveNoteID(args)
+		if err != nil {
+			return err
+		}
+
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+		note, err := sess.client.GetNote(cmd.Context(), noteID)
+		if err != nil {
+			return err
+		}
+
+		dir, err := os.MkdirTemp("", "codestash-edit-")
+		if err != nil {
+			return err
+		}
+		// The directory is kept if the upload fails so no edits are lost.
+		keep := false
+		defer func() {
+			if !keep {
+				os.RemoveAll(dir)
+			}
+		}()
+
+		// The extension lets the editor pick a syntax mode.
+		ext := lang.Extension(note.Language)
+		if ext == "" {
+			ext = ".txt"
+		}
+		codeFile := filepath.Join(dir, note.ID+ext)
+		noteFile := filepath.Join(dir, note.ID+".md")
+		if err := os.WriteFile(codeFile, []byte(note.Code), 0o600); err != nil {
+			return err
+		}
+		if err := os.WriteFile(noteFile, []byte(note.Note), 0o600); err != nil {
+			return err
+		}
+
+		if err := runEditor(codeFile, noteFile); err != nil {
+			return fmt.Errorf("%w; note not updated", err)
+		}
+
+		code, err := os.ReadFile(codeFile)
+		if err != nil {
+			return err
+		}
+		desc, err := os.ReadFile(noteFile)
+		if err != nil {
+			return err
+		}
+
+		var req api.UpdateNoteRequest
+		if s := string(code); s != note.Code {
+			req.Code = &s
+		}
+		if s := string(desc); s != note.Note {
+			req.Note = &s
+		}
+		if req.Code == nil && req.Note == nil {
+			cmd.Println("No changes; note not updated.")
+			return nil
+		}
+
+		pre := api.Precondition{Revision: note.Revision, UpdatedAt: note.UpdatedAt}
+		updated, err := sess.client.UpdateNote(cmd.Context(), note.ID, req, pre)
+		if err != nil {
+			keep = true
+			if api.IsEditConflict(err) {
+				return fmt.Errorf("note %s changed remotely while you were editing; your edits are kept in %s", note.ID, dir)
+			}
+			return fmt.Errorf("%w; your edits are kept in %s", err, dir)
+		}
+
+		st := requireState()
+		if st.Scope() == state.ScopeNote && st.CurrentNoteID == note.ID && updated != nil {
+			st.CurrentNoteUpdatedAt = updated.UpdatedAt
+			if err := st.Save(); err != nil {
+				return err
+			}
+		}
+		cmd.Printf("Updated note %s (%s)\n", note.Title, note.ID)
+		return nil
+	},
+}
+
+func init() {
+	notesCmd.AddCommand(notesEditCmd)
+}
+
+// runEditor opens files in the user's editor and waits for it to exit.
+func runEditor(files ...string) error {
+	editor := os.Getenv("VISUAL")
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// Editors are often configured with flags, e.g. "code --wait".
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], files...)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return fmt.Errorf("editor exited with status %d", exit.ExitCode())
+		}
+		return fmt.Errorf("run editor %q: %w", fields[0], err)
+	}
+	return nil
+}
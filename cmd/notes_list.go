@@ -1,89 +1,237 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/k-kanke/code-stash-cli/internal/api"
-	"github.com/k-kanke/code-stash-cli/internal/auth"
-	"github.com/k-kanke/code-stash-cli/internal/config"
+	"github.com/k-kanke/code-stash-cli/internal/output"
+)
+
+var (
+	noteListColumns  []string
+	noteListSort     string
+	noteListLimit    int
+	noteListTags     []string
+	noteListLang     string
+	noteListSince    string
+	noteListQuery    string
+	noteListAll      bool
+	noteListPageSize int
 )
 
 var notesListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List notes in the current context",
+	Long: `List notes in the current context.
+
+The table fits the terminal width, truncating titles, tags and snippets as
+needed. Pick columns with --columns from id, title, lang, tags, folder,
+updated and snippet.
+
+Filters are sent to the server and also applied locally, so they work with
+servers that ignore them. Locally, every word of --query must appear in the
+title, a tag or the snippet; use ` + "`codestash search`" + ` to search code and
+descriptions. --since takes a date (2006-01-02), an RFC 3339 time, or an
+age such as 36h, 7d or 2w.
+
+Notes are fetched --page-size at a time until --limit notes have arrived,
+then sorted, so --sort orders the first --limit notes in the server's order.
+When more notes match, a hint on stderr says so. --all fetches and sorts
+every note regardless of --limit, which can take a while for a large
+collection.`,
+	Annotations: rendersDocuments(),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		st := requireState()
-		ctx, err := st.Current()
+		columns, err := parseNoteColumns(noteListColumns)
 		if err != nil {
 			return err
 		}
-
-		cfg, err := config.Load()
-		if err != nil {
-			return err
+		if noteListLimit < 1 && !noteListAll {
+			return errors.New("--limit must be positive; use --all to list every note")
 		}
-		token, err := auth.LoadToken(cfg.TokenPath)
-		if err != nil {
-			return err
+		if noteListPageSize < 1 {
+			return errors.New("--page-size must be positive")
 		}
-		if token == nil {
-			return fmt.Errorf("not logged in; run `codestash login` first")
+		compare, ok := noteSorts[noteListSort]
+		if !ok {
+			return fmt.Errorf("unknown sort key %q (want updated, title or server)", noteListSort)
 		}
 
-		client, err := api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret)
+		st := requireState()
+		ctx, err := st.Current()
 		if err != nil {
 			return err
 		}
 
-		notes, err := client.ListNotes(cmd.Context(), token.AccessToken, ctx.Collection)
+		sess, err := newSession()
 		if err != nil {
 			return err
 		}
 
-		filtered := filterNotesByFolder(notes, ctx.Folder)
-		if len(filtered) == 0 {
-			cmd.Println("No notes found for this folder.")
-			return nil
+		filter := api.NoteFilter{
+			FolderID: strings.TrimSpace(ctx.Folder),
+			Tags:     noteListTags,
+			Language: strings.TrimSpace(noteListLang),
+			Query:    strings.TrimSpace(noteListQuery),
+		}
+		if noteListSince != "" {
+			if filter.UpdatedSince, err = parseSince(noteListSince, time.Now()); err != nil {
+				return err
+			}
 		}
 
-		printNotesTable(cmd, filtered)
-		return nil
+		limit := noteListLimit
+		if noteListAll {
+			limit = 0
+		}
+		// Stop fetching at the first note past the limit; it only tells us
+		// that there are more.
+		filtered, more := []api.NoteSummary{}, false
+		for n, err := range sess.client.Notes(cmd.Context(), ctx.Collection, filter, noteListPageSize) {
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(filtered) == limit {
+				more = true
+				break
+			}
+			filtered = append(filtered, n)
+		}
+		if compare != nil {
+			slices.SortStableFunc(filtered, compare)
+		}
+		err = render(cmd, filtered, func() error {
+			if len(filtered) == 0 {
+				filtering := len(filter.Tags) > 0 || filter.Language != "" || !filter.UpdatedSince.IsZero() || filter.Query != ""
+				if !filtering {
+					cmd.Println("No notes found for this folder.")
+				} else {
+					cmd.Println("No notes match the filters.")
+				}
+				return nil
+			}
+			return printNotesTable(cmd, filtered, columns)
+		})
+		if err == nil && more {
+			cmd.PrintErrf("Showing the first %d notes; more match. Raise --limit or use --all to list them.\n", limit)
+		}
+		return err
 	},
 }
 
 func init() {
 	notesCmd.AddCommand(notesListCmd)
+
+	notesListCmd.Flags().StringSliceVar(&noteListColumns, "columns", []string{"id", "title", "lang", "tags", "updated"}, "comma-separated columns to show")
+	notesListCmd.Flags().StringVar(&noteListSort, "sort", "updated", "sort by updated (newest first), title, or server (the server's order)")
+	notesListCmd.Flags().IntVar(&noteListLimit, "limit", 50, "fetch and show at most this many notes")
+	notesListCmd.Flags().StringSliceVar(&noteListTags, "tag", nil, "only notes with this tag (repeatable; all must match)")
+	notesListCmd.Flags().StringVar(&noteListLang, "lang", "", "only notes in this language")
+	notesListCmd.Flags().StringVar(&noteListSince, "since", "", "only notes updated since a date, time or age (e.g. 7d)")
+	notesListCmd.Flags().BoolVar(&noteListAll, "all", false, "fetch and list every note, ignoring --limit")
+	notesListCmd.Flags().IntVar(&noteListPageSize, "page-size", 50, "notes to fetch per request")
+	notesListCmd.Flags().StringVar(&noteListQuery, "query", "", "only notes with every word of this text in their title, tags or snippet")
 }
 
-func filterNotesByFolder(notes []api.NoteSummary, folderID string) []api.NoteSummary {
-	if strings.TrimSpace(folderID) == "" {
-		return notes
-	}
-	result := make([]api.NoteSummary, 0, len(notes))
-	for _, note := range notes {
-		if note.FolderID == nil {
-			continue
+// noteColumns are the columns `notes list --columns` can show.
+var noteColumns = map[string]struct {
+	column output.Column
+	value  func(n api.NoteSummary, now time.Time) string
+}{
+	"id":      {output.Column{Header: "ID"}, func(n api.NoteSummary, _ time.Time) string { return n.ID }},
+	"title":   {output.Column{Header: "TITLE", Flexible: true, MinWidth: 10}, func(n api.NoteSummary, _ time.Time) string { return n.Title }},
+	"lang":    {output.Column{Header: "LANG"}, func(n api.NoteSummary, _ time.Time) string { return n.Language }},
+	"tags":    {output.Column{Header: "TAGS", Flexible: true, MinWidth: 6}, func(n api.NoteSummary, _ time.Time) string { return strings.Join(n.Tags, ",") }},
+	"folder":  {output.Column{Header: "FOLDER"}, func(n api.NoteSummary, _ time.Time) string { return deref(n.FolderID) }},
+	"updated": {output.Column{Header: "UPDATED"}, func(n api.NoteSummary, now time.Time) string { return output.RelativeTime(n.UpdatedAt, now) }},
+	"snippet": {output.Column{Header: "SNIPPET", Flexible: true, MinWidth: 10}, func(n api.NoteSummary, _ time.Time) string { return oneLine(n.Snippet) }},
+}
+
+var noteColumnAliases = map[string]string{"language": "lang", "updated_at": "updated", "folder_id": "folder"}
+
+func parseNoteColumns(names []string) ([]string, error) {
+	cols := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := noteColumnAliases[name]; ok {
+			name = alias
 		}
-		if *note.FolderID == folderID {
-			result = append(result, note)
+		if _, ok := noteColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q (want id, title, lang, tags, folder, updated or snippet)", name)
+		}
+		cols = append(cols, name)
+	}
+	if len(cols) == 0 {
+		return nil, errors.New("--columns needs at least one column")
+	}
+	return cols, nil
+}
+
+// noteSorts are the orders `notes list --sort` accepts. "server" has no
+// comparison: notes stay in the order the server sent them.
+var noteSorts = map[string]func(a, b api.NoteSummary) int{
+	"updated": func(a, b api.NoteSummary) int { return b.UpdatedAt.Compare(a.UpdatedAt) },
+	"title": func(a, b api.NoteSummary) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	},
+	"server": nil,
+}
+
+func printNotesTable(cmd *cobra.Command, notes []api.NoteSummary, columns []string) error {
+	now := time.Now()
+	t := output.Table{}
+	for _, name := range columns {
+		t.Columns = append(t.Columns, noteColumns[name].column)
+	}
+	for _, n := range notes {
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			row[i] = noteColumns[name].value(n, now)
 		}
+		t.Rows = append(t.Rows, row)
 	}
-	return result
+	return t.Write(cmd.OutOrStdout(), terminalWidth(cmd.OutOrStdout()))
 }
 
-func printNotesTable(cmd *cobra.Command, notes []api.NoteSummary) {
-	cmd.Printf("%-36s  %-30s  %-19s\n", "ID", "Title", "Updated")
-	cmd.Println(strings.Repeat("-", 90))
-	for _, note := range notes {
-		updated := note.UpdatedAt.Format(time.RFC3339)
-		title := note.Title
-		if len(title) > 30 {
-			title = title[:27] + "..."
+// parseSince parses --since: an RFC 3339 time, a date, or an age before now
+// as a Go duration or a number of days ("7d") or weeks ("2w").
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if n, unit := strings.TrimRight(s, "dw"), strings.TrimLeft(s, "0123456789"); unit == "d" || unit == "w" {
+		days, err := strconv.Atoi(n)
+		if err == nil && days >= 0 {
+			if unit == "w" {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
 		}
-		cmd.Printf("%-36s  %-30s  %-19s\n", note.ID, title, updated)
 	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want a date (2006-01-02), an RFC 3339 time or an age such as 36h or 7d", s)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// oneLine collapses whitespace, including line breaks, to single spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/fsutil"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+var (
+	notePullFile  string
+	notePullForce bool
+)
+
+var notesPullCmd = &cobra.Command{
+	Use:   "pull [note-id]",
+	Short: "Write a note's code to a local file",
+	Long: `Write a note's code to a local file and remember the mapping.
+
+Without an ID the note of the current note scope is pulled. Without --file
+the note is written to the file it is already mapped to.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noteID, err := resolveNoteID(args)
+		if err != nil {
+			return err
+		}
+
+		st := requireState()
+		ctx, err := st.Current()
+		if err != nil {
+			return err
+		}
+
+		target := strings.TrimSpace(notePullFile)
+		if target == "" {
+			rel, ok := st.FileForNote(ctx.Name, noteID)
+			if !ok {
+				return fmt.Errorf("note %s is not mapped to a file; use --file", noteID)
+			}
+			target = filepath.Join(projectRoot, filepath.FromSlash(rel))
+		}
+		absFile, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+		note, err := sess.client.GetNote(cmd.Context(), noteID)
+		if err != nil {
+			return err
+		}
+
+		doc := pulledNoteDoc{ID: note.ID, Title: note.Title, File: relativeToRoot(absFile)}
+		existing, err := os.ReadFile(absFile)
+		switch {
+		case err == nil:
+			if bytes.Equal(existing, []byte(note.Code)) {
+				break
+			}
+			if !notePullForce {
+				return fmt.Errorf("%s exists and differs from the note; use --force to overwrite", relativeToRoot(absFile))
+			}
+			fallthrough
+		case errors.Is(err, os.ErrNotExist):
+			if err := fsutil.WriteFileAtomic(absFile, []byte(note.Code), 0o644); err != nil {
+				return fmt.Errorf("write file: %w", err)
+			}
+			doc.Written = true
+		default:
+			return fmt.Errorf("read file: %w", err)
+		}
+
+		if err := st.SaveBase([]byte(note.Code)); err != nil {
+			return err
+		}
+		st.SetFileMapping(ctx.Name, doc.File, state.SyncedMapping(note.ID, []byte(note.Code), note.UpdatedAt, note.Revision))
+		if err := st.Save(); err != nil {
+			return err
+		}
+
+		return render(cmd, doc, func() error {
+			if doc.Written {
+				cmd.Printf("Pulled note %q (%s) into %s\n", doc.Title, doc.ID, doc.File)
+			} else {
+				cmd.Printf("%s is already up to date\n", doc.File)
+			}
+			return nil
+		})
+	},
+}
+
+// pulledNoteDoc is the --output document of `notes pull`.
+type pulledNoteDoc struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	File  string `json:"file"`
+	// Written is false when the file already had the note's code.
+	Written bool `json:"written"`
+}
+
+func init() {
+	notesCmd.AddCommand(notesPullCmd)
+
+	notesPullCmd.Flags().StringVar(&notePullFile, "file", "", "path to write the code to")
+	notesPullCmd.Flags().BoolVar(&notePullForce, "force", false, "overwrite a local file with different content")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/highlight"
+)
+
+var notesShowCmd = &cobra.Command{
+	Use:   "show [note-id]",
+	Short: "Show a note's code and description",
+	Long: `Show a note's code and description.
+
+Without an ID the note of the current note scope is shown. Code is
+syntax-highlighted when stdout is a terminal.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noteID, err := resolveNoteID(args)
+		if err != nil {
+			return err
+		}
+
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+		note, err := sess.client.GetNote(cmd.Context(), noteID)
+		if err != nil {
+			return err
+		}
+
+		return render(cmd, note, func() error {
+			printNote(cmd, note)
+			return nil
+		})
+	},
+}
+
+func init() {
+	notesCmd.AddCommand(notesShowCmd)
+}
+
+// resolveNoteID returns the note ID given as the first argument, falling
+// back to the note of the current note scope.
+func resolveNoteID(args []string) (string, error) {
+	if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+		return strings.TrimSpace(args[0]), nil
+	}
+	noteID, _, err := requireState().CurrentNote()
+	if err != nil {
+		return "", errNoteIDRequired
+	}
+	return noteID, nil
+}
+
+func printNote(cmd *cobra.Command, note *api.Note) {
+	color := useColor(cmd.OutOrStdout())
+
+	cmd.Printf("%s (%s)\n", note.Title, note.ID)
+	if note.Language != "" {
+		cmd.Printf("Language: %s\n", note.Language)
+	}
+	if len(note.Tags) > 0 {
+		cmd.Printf("Tags: %s\n", strings.Join(note.Tags, ", "))
+	}
+	if note.FolderID != nil {
+		cmd.Printf("Folder: %s\n", *note.FolderID)
+	}
+	if !note.CreatedAt.IsZero() {
+		cmd.Printf("Created: %s\n", note.CreatedAt.Format(time.RFC3339))
+	}
+	cmd.Printf("Updated: %s\n", note.UpdatedAt.Format(time.RFC3339))
+
+	cmd.Println()
+	code := strings.TrimRight(note.Code, "\n")
+	if color {
+		code = highlight.Code(code, note.Language)
+	}
+	cmd.Println(code)
+
+	if strings.TrimSpace(note.Note) != "" {
+		cmd.Println()
+		cmd.Println(strings.Repeat("-", 40))
+		cmd.Println(strings.TrimRight(note.Note, "\n"))
+	}
+}
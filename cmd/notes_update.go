@@ -1,17 +1,18 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/k-kanke/code-stash-cli/internal/api"
-	"github.com/k-kanke/code-stash-cli/internal/auth"
-	"github.com/k-kanke/code-stash-cli/internal/config"
+	"github.com/k-kanke/code-stash-cli/internal/diff"
+	"github.com/k-kanke/code-stash-cli/internal/fsutil"
+	"github.com/k-kanke/code-stash-cli/internal/state"
 )
 
 var (
@@ -20,17 +21,39 @@ var (
 	noteUpdateLang     string
 	noteUpdateTags     []string
 	noteUpdateNoteFile string
+	noteUpdateForce    bool
+	noteUpdateMarkers  bool
+	noteUpdateCode     string
 )
 
 var notesUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update an existing note based on a local file",
+	Long: `Update the current note with the contents of a local file.
+
+The code comes from --file, from stdin with --file -, or inline with --code;
+the note body from --note <path> or --note -. Only a code file is mapped to
+the note and can be merged into.
+
+The update only succeeds if the note is unchanged since the file was last
+pushed or pulled (or since note scope was entered). If someone else edited
+it in the meantime, you are offered a three-way merge of their changes and
+yours when running in a terminal; --force overwrites their changes instead.
+
+Code that still contains the conflict markers a merge writes is rejected.
+Pass --allow-conflict-markers if the markers are meant to be there, e.g. in
+documentation or test fixtures.`,
+	Annotations:  rendersDocuments(),
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := requireNoteScope(); err != nil {
 			return err
 		}
-		if strings.TrimSpace(noteUpdateFile) == "" {
-			return errors.New("--file is required")
+		if err := checkCodeSource(cmd, noteUpdateFile); err != nil {
+			return err
+		}
+		if err := checkStdinOnce(noteUpdateFile, noteUpdateNoteFile); err != nil {
+			return err
 		}
 
 		st := requireState()
@@ -39,43 +62,21 @@ var notesUpdateCmd = &cobra.Command{
 			return err
 		}
 
-		absFile, err := filepath.Abs(noteUpdateFile)
+		fileContent, absFile, err := readCode(cmd, noteUpdateFile, noteUpdateCode)
 		if err != nil {
 			return err
 		}
-
-		fileContent, err := os.ReadFile(absFile)
-		if err != nil {
-			return fmt.Errorf("read file: %w", err)
-		}
 		var noteContent *string
 		if strings.TrimSpace(noteUpdateNoteFile) != "" {
-			noteAbs, err := filepath.Abs(noteUpdateNoteFile)
+			body, err := readInput(cmd, noteUpdateNoteFile, "note body")
 			if err != nil {
 				return err
 			}
-			body, err := os.ReadFile(noteAbs)
-			if err != nil {
-				return fmt.Errorf("read note body: %w", err)
-			}
 			content := string(body)
 			noteContent = &content
 		}
 
-		cfg, err := config.Load()
-		if err != nil {
-			return err
-		}
-
-		token, err := auth.LoadToken(cfg.TokenPath)
-		if err != nil {
-			return err
-		}
-		if token == nil {
-			return errors.New("not logged in; run `codestash login` first")
-		}
-
-		client, err := api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret)
+		sess, err := newSession()
 		if err != nil {
 			return err
 		}
@@ -109,25 +110,162 @@ var notesUpdateCmd = &cobra.Command{
 			req.Note = noteContent
 		}
 
-		if err := client.UpdateNote(cmd.Context(), token.AccessToken, noteID, req); err != nil {
+		ctx, err := st.Current()
+		if err != nil {
 			return err
 		}
+		u := noteUpdate{
+			st: st, sess: sess, ctxName: ctx.Name, noteID: noteID,
+			absFile: absFile, req: req,
+		}
+		if absFile != "" {
+			u.rel = relativeToRoot(absFile)
+			u.mapping, u.mapped = st.GetFileMapping(ctx.Name, u.rel)
+		}
+
+		if !noteUpdateMarkers && diff.HasConflictMarkers(codeStr) {
+			return errors.New("the code still has conflict markers; resolve them, or pass --allow-conflict-markers to keep them")
+		}
+		pre := api.Precondition{UpdatedAt: st.CurrentNoteUpdatedAt}
+		if u.mapped && u.mapping.NoteID == noteID {
+			pre = mappingPrecondition(u.mapping)
+		}
+		if noteUpdateForce {
+			pre = api.Precondition{}
+		}
 
-		target := noteID
-		if noteTitle != "" {
-			target = fmt.Sprintf("%s (%s)", noteTitle, noteID)
+		err = u.push(cmd, fileContent, pre)
+		if api.IsEditConflict(err) {
+			err = u.resolveConflict(cmd, fileContent)
 		}
-		cmd.Printf("Updated note %s\n", target)
-		return nil
+		if err != nil {
+			return err
+		}
+
+		doc := updatedNoteDoc{ID: noteID, Title: noteTitle, File: u.rel}
+		return render(cmd, doc, func() error {
+			target := noteID
+			if noteTitle != "" {
+				target = fmt.Sprintf("%s (%s)", noteTitle, noteID)
+			}
+			cmd.Printf("Updated note %s\n", target)
+			return nil
+		})
 	},
 }
 
+// updatedNoteDoc is the --output document of `notes update`.
+type updatedNoteDoc struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	// File is the file the code came from, if any.
+	File string `json:"file,omitempty"`
+}
+
 func init() {
 	notesCmd.AddCommand(notesUpdateCmd)
 
-	notesUpdateCmd.Flags().StringVar(&noteUpdateFile, "file", "", "path to code file")
+	notesUpdateCmd.Flags().StringVar(&noteUpdateFile, "file", "", "path to code file, or - for stdin")
+	notesUpdateCmd.Flags().StringVar(&noteUpdateCode, "code", "", "code given inline instead of --file")
 	notesUpdateCmd.Flags().StringVar(&noteUpdateTitle, "title", "", "new title")
 	notesUpdateCmd.Flags().StringVar(&noteUpdateLang, "language", "", "code language")
 	notesUpdateCmd.Flags().StringSliceVar(&noteUpdateTags, "tags", nil, "comma-separated tags")
-	notesUpdateCmd.Flags().StringVar(&noteUpdateNoteFile, "note", "", "path to note/description file")
+	notesUpdateCmd.Flags().StringVar(&noteUpdateNoteFile, "note", "", "path to note/description file, or - for stdin")
+	notesUpdateCmd.Flags().BoolVar(&noteUpdateForce, "force", false, "overwrite the note even if it changed remotely")
+	notesUpdateCmd.Flags().BoolVar(&noteUpdateMarkers, "allow-conflict-markers", false, "push code that contains conflict markers")
+	notesUpdateCmd.MarkFlagsMutuallyExclusive("file", "code")
+}
+
+// noteUpdate carries what `notes update` needs to push a file, possibly
+// more than once when resolving a conflict.
+type noteUpdate struct {
+	st      *state.State
+	sess    *session
+	ctxName string
+	noteID  string
+	absFile string
+	rel     string
+	req     api.UpdateNoteRequest
+	mapping state.FileMapping
+	mapped  bool
+}
+
+// push sends content and records it as synced. A file already mapped to a
+// different note keeps its mapping.
+func (u *noteUpdate) push(cmd *cobra.Command, content []byte, pre api.Precondition) error {
+	code := string(content)
+	req := u.req
+	req.Code = &code
+	updated, err := u.sess.client.UpdateNote(cmd.Context(), u.noteID, req, pre)
+	if err != nil {
+		return err
+	}
+	if u.rel != "" && (!u.mapped || u.mapping.NoteID == u.noteID) {
+		if updated, err = recordSynced(cmd, u.sess, u.st, u.ctxName, u.rel, u.noteID, content, updated); err != nil {
+			return err
+		}
+	}
+	if updated != nil {
+		u.st.CurrentNoteUpdatedAt = updated.UpdatedAt
+	}
+	return u.st.Save()
+}
+
+// resolveConflict handles a rejected update: scripts get an error, while a
+// terminal user may merge the remote changes into the file or overwrite them.
+func (u *noteUpdate) resolveConflict(cmd *cobra.Command, local []byte) error {
+	if u.rel == "" {
+		return fmt.Errorf("note %s changed remotely since it was last seen; use --force to overwrite it, or update from a file to merge", u.noteID)
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("note %s changed remotely since %s was last synced; use --force to overwrite it, or run in a terminal to merge", u.noteID, u.rel)
+	}
+
+	promptf(cmd, "Note %s changed remotely since %s was last synced.\n", u.noteID, u.rel)
+	promptf(cmd, "[m]erge remote changes into the file, [o]verwrite them, or [a]bort? ")
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "m", "merge":
+	case "o", "overwrite":
+		return u.push(cmd, local, api.Precondition{})
+	default:
+		return errors.New("update aborted")
+	}
+
+	remote, err := u.sess.client.GetNote(cmd.Context(), u.noteID)
+	if err != nil {
+		return err
+	}
+	var base []byte
+	if u.mapped && u.mapping.NoteID == u.noteID {
+		base, err = u.st.Base(u.mapping)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if base == nil {
+		promptf(cmd, "No common base was recorded for this file; every difference is treated as a conflict.\n")
+	}
+
+	merged := diff.Merge(string(base), string(local), remote.Code)
+	if err := fsutil.WriteFileAtomic(u.absFile, []byte(merged.Text), 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if merged.Conflicts > 0 {
+		// The file now builds on the remote version, so the next update
+		// goes through once the markers are resolved.
+		u.st.CurrentNoteUpdatedAt = remote.UpdatedAt
+		if !u.mapped || u.mapping.NoteID == u.noteID {
+			if _, err := recordSynced(cmd, u.sess, u.st, u.ctxName, u.rel, u.noteID, []byte(remote.Code), remote); err != nil {
+				return err
+			}
+		}
+		if err := u.st.Save(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d conflict(s) written to %s; resolve them and run `codestash notes update --file %s` again", merged.Conflicts, u.rel, u.rel)
+	}
+
+	promptf(cmd, "Merged remote changes into %s.\n", u.rel)
+	return u.push(cmd, []byte(merged.Text), api.Precondition{Revision: remote.Revision, UpdatedAt: remote.UpdatedAt})
 }
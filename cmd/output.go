@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/k-kanke/code-stash-cli/internal/output"
+)
+
+// outFormat is the parsed --output flag, set before any command runs.
+var outFormat output.Format
+
+// documentsAnnotation marks the commands that render --output documents.
+// The others only print text.
+const documentsAnnotation = "codestash/output-documents"
+
+// rendersDocuments is the Annotations value of commands that call render.
+func rendersDocuments() map[string]string {
+	return map[string]string{documentsAnnotation: "true"}
+}
+
+func parseOutputFlag(cmd *cobra.Command, args []string) error {
+	f, err := output.Parse(viper.GetString("output"))
+	if err != nil {
+		return err
+	}
+	// Asking a text-only command for a document is a mistake worth
+	// reporting before it changes anything. A format from
+	// CODESTASH_OUTPUT or the config is a default for the commands that
+	// support it, so the others still run and print text.
+	if !f.IsText() && cmd.Annotations[documentsAnnotation] == "" {
+		if cmd.Flags().Changed("output") {
+			return fmt.Errorf("%s prints text only; --output %s is not supported", cmd.CommandPath(), f.Kind)
+		}
+		f = output.Format{}
+	}
+	outFormat = f
+	return nil
+}
+
+// render writes v in the --output format, or calls text for the default
+// human-readable output. Commands build v from stable document types so
+// that scripts do not break when the text output changes.
+func render(cmd *cobra.Command, v any, text func() error) error {
+	if outFormat.IsText() {
+		return text()
+	}
+	return outFormat.Write(cmd.OutOrStdout(), v)
+}
+
+// printText is cmd.Printf for progress and hints that only belong in the
+// text output, and would break a document on stdout.
+func printText(cmd *cobra.Command, format string, args ...any) {
+	if outFormat.IsText() {
+		cmd.Printf(format, args...)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/config"
+)
+
+var (
+	profileAddAPIURL       string
+	profileAddClientID     string
+	profileAddClientSecret string
+	profileAddTokenPath    string
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage config profiles for different hosts or accounts",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List configured profiles",
+	Annotations: rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(profiles.Profiles))
+		for name := range profiles.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// The client secret stays out of the document.
+		docs := make([]profileDoc, 0, len(names))
+		for _, name := range names {
+			p := profiles.Profiles[name]
+			docs = append(docs, profileDoc{
+				Name:       name,
+				APIBaseURL: p.APIBaseURL,
+				ClientID:   p.ClientID,
+				TokenPath:  p.TokenPath,
+				Current:    name == profiles.Current,
+			})
+		}
+
+		return render(cmd, docs, func() error {
+			for _, d := range docs {
+				marker := " "
+				if d.Current {
+					marker = "*"
+				}
+				host := d.APIBaseURL
+				if host == "" {
+					host = "<default>"
+				}
+				cmd.Printf("%s %s (%s)\n", marker, d.Name, host)
+			}
+			if len(docs) == 0 {
+				cmd.Println("No profiles defined. Run `codestash profile add <name> --api-url <url>` to create one.")
+			}
+			return nil
+		})
+	},
+}
+
+// profileDoc is a profile in the --output document of `profile list`.
+type profileDoc struct {
+	Name       string `json:"name"`
+	APIBaseURL string `json:"api_base_url,omitempty"`
+	ClientID   string `json:"client_id,omitempty"`
+	TokenPath  string `json:"token_path,omitempty"`
+	Current    bool   `json:"current"`
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or replace a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimSpace(args[0])
+		if strings.TrimSpace(profileAddAPIURL) == "" {
+			return errors.New("api url is required (use --api-url)")
+		}
+
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		if err := profiles.Add(config.Profile{
+			Name:         name,
+			APIBaseURL:   strings.TrimSpace(profileAddAPIURL),
+			ClientID:     strings.TrimSpace(profileAddClientID),
+			ClientSecret: profileAddClientSecret,
+			TokenPath:    strings.TrimSpace(profileAddTokenPath),
+		}); err != nil {
+			return err
+		}
+		if err := profiles.Save(); err != nil {
+			return err
+		}
+
+		cmd.Printf("Added profile %q\n", name)
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a profile the default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if err := profiles.Use(name); err != nil {
+			return err
+		}
+		if err := profiles.Save(); err != nil {
+			return err
+		}
+		cmd.Printf("Using profile %q\n", name)
+		return nil
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if err := profiles.Remove(name); err != nil {
+			return err
+		}
+		if err := profiles.Save(); err != nil {
+			return err
+		}
+		cmd.Printf("Removed profile %q\n", name)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+
+	profileAddCmd.Flags().StringVar(&profileAddAPIURL, "api-url", "", "API base URL")
+	profileAddCmd.Flags().StringVar(&profileAddClientID, "client-id", "", "OAuth client ID (defaults to the top-level config)")
+	profileAddCmd.Flags().StringVar(&profileAddClientSecret, "client-secret", "", "OAuth client secret (defaults to the top-level config)")
+	profileAddCmd.Flags().StringVar(&profileAddTokenPath, "token-path", "", "token file (defaults to ~/.config/codestash/tokens/<name>.json)")
+}
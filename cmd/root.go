@@ -22,16 +22,22 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/k-kanke/code-stash-cli/internal/config"
 	"github.com/k-kanke/code-stash-cli/internal/state"
 )
 
+// version is set at build time with -ldflags "-X .../cmd.version=...".
+var version = "dev"
+
 var (
 	cfgFile     string
 	appState    *state.State
@@ -48,6 +54,7 @@ examples and usage of using your application. For example:
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
+	PersistentPreRunE: parseOutputFlag,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -56,11 +63,40 @@ to quickly create a Cobra application.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// Cobra writes Print* output to stderr unless told otherwise; command
+	// output belongs on stdout so it can be piped.
+	rootCmd.SetOut(os.Stdout)
 	if err := rootCmd.Execute(); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
 		os.Exit(1)
 	}
 }
 
+// exitError makes Execute exit with a specific status. Without err it is
+// silent, for commands whose exit status is itself the answer.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// exitStatus ends cmd with status code and no error message.
+func exitStatus(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	return &exitError{code: code}
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
@@ -71,6 +107,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.code-stash-cli.yaml)")
 	rootCmd.PersistentFlags().String("root", ".", "project root for codestash state")
 	_ = viper.BindPFlag("project_root", rootCmd.PersistentFlags().Lookup("root"))
+	rootCmd.PersistentFlags().String("profile", "", "config profile to use (overrides the context's pinned profile)")
+	_ = viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	_ = viper.BindEnv("profile", "CODESTASH_PROFILE")
+	rootCmd.PersistentFlags().Int("retries", 2, "retries for transient API failures on idempotent requests")
+	_ = viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	rootCmd.PersistentFlags().Bool("debug", false, "log HTTP requests to stderr")
+	_ = viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	rootCmd.PersistentFlags().StringP("output", "o", "text", "output format of commands that print documents: text, json, yaml, ndjson or template=<go template>")
+	_ = viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	_ = viper.BindEnv("output", "CODESTASH_OUTPUT")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -93,7 +139,7 @@ func initConfig() {
 		viper.SetConfigName(".code-stash-cli")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	config.ReadEnv() // read in CODESTASH_* environment variables that match
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -119,6 +165,22 @@ func requireState() *state.State {
 	return appState
 }
 
+// loadConfig loads the config for the profile selected by --profile, the
+// current context's pinned profile, or the current profile, in that order.
+func loadConfig() (*config.Config, error) {
+	return config.Load(activeProfile())
+}
+
+func activeProfile() string {
+	if name := strings.TrimSpace(viper.GetString("profile")); name != "" {
+		return name
+	}
+	if ctx, err := requireState().Current(); err == nil {
+		return ctx.Profile
+	}
+	return ""
+}
+
 func statePath() string {
 	return projectRoot
 }
@@ -134,6 +196,8 @@ func relativeToRoot(absPath string) string {
 	return filepath.ToSlash(rel)
 }
 
+var errNoteIDRequired = errors.New("note id is required outside note scope; pass one or run `codestash note switch <id>` first")
+
 func requireFolderScope() error {
 	st := requireState()
 	if st.Scope() != state.ScopeFolder {
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/output"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+// maxMatchLines caps the matching lines printed per note.
+const maxMatchLines = 5
+
+var searchFieldNames = []string{"title", "code", "note", "tags"}
+
+var (
+	searchAllContexts bool
+	searchFields      []string
+	searchLimit       int
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search notes by title, code, description and tags",
+	Long: `Search the notes of the current context for text in their title, code,
+description and tags, and print each match with its matching lines.
+
+--all-contexts searches every context instead, each with its own profile.
+Narrow the fields searched with --field, e.g. --field code,tags.`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.TrimSpace(strings.Join(args, " "))
+		if query == "" {
+			return errors.New("search query is required")
+		}
+		for _, f := range searchFields {
+			if !slices.Contains(searchFieldNames, f) {
+				return fmt.Errorf("unknown field %q (want %s)", f, strings.Join(searchFieldNames, ", "))
+			}
+		}
+		if searchLimit < 0 {
+			return errors.New("--limit must not be negative")
+		}
+
+		contexts, err := searchContexts()
+		if err != nil {
+			return err
+		}
+		docs, err := searchAll(cmd, contexts, query)
+		if err != nil {
+			return err
+		}
+
+		return render(cmd, docs, func() error {
+			if len(docs) == 0 {
+				cmd.Printf("No notes match %q.\n", query)
+				return nil
+			}
+			printSearchResults(cmd, docs, query)
+			if searchAllContexts {
+				cmd.Println("\nOpen a note with `codestash context switch <context>`, then `codestash note switch <id>`.")
+			} else {
+				cmd.Println("\nOpen a note with `codestash note switch <id>`.")
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().BoolVar(&searchAllContexts, "all-contexts", false, "search every context, not just the current one")
+	searchCmd.Flags().StringSliceVar(&searchFields, "field", nil, "fields to search: title, code, note, tags (default all)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 0, "show at most this many notes (0 for the server's default)")
+}
+
+// searchDoc is a search result in --output documents.
+type searchDoc struct {
+	Context string `json:"context"`
+	api.SearchResult
+}
+
+func searchContexts() ([]state.Context, error) {
+	st := requireState()
+	if !searchAllContexts {
+		ctx, err := st.Current()
+		if err != nil {
+			return nil, err
+		}
+		return []state.Context{ctx}, nil
+	}
+	if len(st.Contexts) == 0 {
+		return nil, errors.New("no contexts defined; run `codestash init --folder <id>` first")
+	}
+	names := make([]string, 0, len(st.Contexts))
+	for name := range st.Contexts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	contexts := make([]state.Context, 0, len(names))
+	for _, name := range names {
+		contexts = append(contexts, st.Contexts[name])
+	}
+	return contexts, nil
+}
+
+// searchAll searches each context with the session of its profile. A
+// failing context is reported and skipped unless every context fails.
+func searchAll(cmd *cobra.Command, contexts []state.Context, query string) ([]searchDoc, error) {
+	override := strings.TrimSpace(viper.GetString("profile"))
+	sessions := make(map[string]*session)
+	// A note found through several contexts on the same profile is listed
+	// once, under the first.
+	seen := make(map[string]bool)
+
+	var docs []searchDoc
+	var lastErr error
+	failed := 0
+	for _, ctx := range contexts {
+		profile := ctx.Profile
+		if override != "" {
+			profile = override
+		}
+		results, err := searchContext(cmd, sessions, profile, ctx, query)
+		if err != nil {
+			if len(contexts) == 1 {
+				return nil, err
+			}
+			cmd.PrintErrf("Search in context %q failed: %v\n", ctx.Name, err)
+			failed++
+			lastErr = err
+			continue
+		}
+		for _, r := range results {
+			key := profile + "\x00" + r.NoteID
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			docs = append(docs, searchDoc{Context: ctx.Name, SearchResult: r})
+		}
+	}
+	if failed == len(contexts) {
+		return nil, lastErr
+	}
+	if searchLimit > 0 && len(docs) > searchLimit {
+		docs = docs[:searchLimit]
+	}
+	if docs == nil {
+		docs = []searchDoc{}
+	}
+	return docs, nil
+}
+
+func searchContext(cmd *cobra.Command, sessions map[string]*session, profile string, ctx state.Context, query string) ([]api.SearchResult, error) {
+	sess, ok := sessions[profile]
+	if !ok {
+		var err error
+		if sess, err = newProfileSession(profile); err != nil {
+			return nil, err
+		}
+		sessions[profile] = sess
+	}
+	return sess.client.Search(cmd.Context(), api.SearchRequest{
+		Query:        query,
+		CollectionID: ctx.Collection,
+		FolderID:     ctx.Folder,
+		Fields:       searchFields,
+		Limit:        searchLimit,
+	})
+}
+
+func printSearchResults(cmd *cobra.Command, docs []searchDoc, query string) {
+	out := cmd.OutOrStdout()
+	color := useColor(out)
+	width := terminalWidth(out)
+	terms := strings.Fields(query)
+
+	for i, doc := range docs {
+		if i > 0 {
+			cmd.Println()
+		}
+		header := doc.NoteID
+		if color {
+			header = "\x1b[1m" + header + "\x1b[0m"
+		}
+		header += "  " + highlightTerms(doc.Title, terms, color)
+		if doc.Language != "" {
+			header += "  [" + doc.Language + "]"
+		}
+		for _, tag := range doc.Tags {
+			header += " #" + highlightTerms(tag, terms, color)
+		}
+		if searchAllContexts {
+			header += "  (context: " + doc.Context + ")"
+		}
+		cmd.Println(header)
+
+		shown := 0
+		for _, m := range doc.Matches {
+			if m.Field == "title" || m.Field == "tags" {
+				// Already visible in the header.
+				continue
+			}
+			if shown == maxMatchLines {
+				cmd.Printf("    … %d more matching lines\n", countLineMatches(doc.Matches)-shown)
+				break
+			}
+			prefix := "    " + m.Field
+			if m.Line > 0 {
+				prefix += fmt.Sprintf(":%d", m.Line)
+			}
+			prefix += ": "
+			text := strings.TrimSpace(m.Text)
+			if width > 0 {
+				text = output.Truncate(text, max(width-output.StringWidth(prefix), 10))
+			}
+			cmd.Println(prefix + highlightTerms(text, terms, color))
+			shown++
+		}
+	}
+}
+
+func countLineMatches(matches []api.SearchMatch) int {
+	n := 0
+	for _, m := range matches {
+		if m.Field != "title" && m.Field != "tags" {
+			n++
+		}
+	}
+	return n
+}
+
+// highlightTerms marks every case-insensitive occurrence of the terms in s.
+func highlightTerms(s string, terms []string, color bool) string {
+	if !color || len(terms) == 0 {
+		return s
+	}
+	const on, off = "\x1b[1;33m", "\x1b[0m"
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		n := 0
+		for _, t := range terms {
+			n = max(n, foldPrefixLen(s[i:], t))
+		}
+		if n > 0 {
+			b.WriteString(on + s[i:i+n] + off)
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	return b.String()
+}
+
+// foldPrefixLen returns the byte length of the prefix of s that equals
+// prefix under Unicode case folding, or 0 if there is none.
+func foldPrefixLen(s, prefix string) int {
+	i := 0
+	for _, want := range prefix {
+		if i >= len(s) {
+			return 0
+		}
+		got, size := utf8.DecodeRuneInString(s[i:])
+		if !strings.EqualFold(string(got), string(want)) {
+			return 0
+		}
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/auth"
+	"github.com/k-kanke/code-stash-cli/internal/config"
+)
+
+// tokenEnv holds a personal access token to use in place of the stored
+// token, e.g. in CI. It is read directly rather than as a config key, so
+// nothing else can stand in for it.
+const tokenEnv = "CODESTASH_TOKEN"
+
+// session bundles what commands need to call the authenticated API.
+type session struct {
+	cfg    *config.Config
+	client *api.Client
+	store  auth.Store
+	tokens *auth.TokenSource
+}
+
+func newSession() (*session, error) {
+	return newProfileSession(activeProfile())
+}
+
+// newProfileSession is newSession for a named config profile, for commands
+// that work across contexts pinned to different profiles.
+func newProfileSession(profile string) (*session, error) {
+	cfg, err := config.Load(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var store auth.Store
+	if token := strings.TrimSpace(os.Getenv(tokenEnv)); token != "" {
+		store = &auth.EnvStore{Variable: tokenEnv, Value: token}
+	} else {
+		store, err = openTokenStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The token source refreshes through the same client it authenticates;
+	// refresh requests go to a public endpoint, so there is no recursion.
+	var client *api.Client
+	tokens := auth.NewTokenSource(store, func(ctx context.Context, refreshToken string) (auth.Token, error) {
+		resp, apiErr, err := client.RefreshToken(ctx, refreshToken)
+		if err != nil {
+			return auth.Token{}, err
+		}
+		if apiErr != nil {
+			return auth.Token{}, fmt.Errorf("%w; run `codestash login` again", apiErr)
+		}
+		return tokenFromResponse(resp), nil
+	})
+	client, err = newClient(cfg, api.WithTokenSource(tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	return &session{
+		cfg:    cfg,
+		client: client,
+		store:  store,
+		tokens: tokens,
+	}, nil
+}
+
+func newClient(cfg *config.Config, opts ...api.Option) (*api.Client, error) {
+	opts = append([]api.Option{
+		api.WithTimeout(cfg.Timeout),
+		api.WithRetryPolicy(api.RetryPolicy{
+			MaxAttempts: cfg.Retries + 1,
+			BaseDelay:   cfg.RetryBaseDelay,
+			MaxDelay:    cfg.RetryMaxDelay,
+		}),
+		api.WithUserAgent("codestash-cli/" + version),
+	}, opts...)
+	if viper.GetBool("debug") {
+		opts = append(opts, api.WithDebug(os.Stderr))
+	}
+	return api.NewClient(cfg.APIBaseURL, cfg.ClientID, cfg.ClientSecret, opts...)
+}
+
+// openTokenStore opens the configured token backend, moving a plaintext
+// token file left by an earlier version into it.
+func openTokenStore(cfg *config.Config) (auth.Store, error) {
+	store, err := auth.NewStore(auth.StoreOptions{
+		Backend:    cfg.TokenStore,
+		Path:       cfg.TokenPath,
+		Passphrase: cfg.TokenPassphrase,
+		Account:    tokenAccount(cfg),
+	})
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := auth.MigrateFile(cfg.TokenPath, store)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		fmt.Fprintf(os.Stderr, "Moved token from %s to %s\n", cfg.TokenPath, store.Location())
+	}
+	return store, nil
+}
+
+// tokenAccount names the token in shared backends such as the keyring.
+func tokenAccount(cfg *config.Config) string {
+	if cfg.Profile != "" {
+		return cfg.Profile
+	}
+	return cfg.APIBaseURL
+}
+
+func tokenFromResponse(resp *api.TokenResponse) auth.Token {
+	var refresh string
+	if resp.RefreshToken != nil {
+		refresh = *resp.RefreshToken
+	}
+	var expiresAt time.Time
+	if resp.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return auth.Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: refresh,
+		Scope:        strings.Fields(resp.Scope),
+		ExpiresAt:    expiresAt,
+	}
+}
@@ -7,39 +7,72 @@ import (
 )
 
 var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show current codestash context and scope",
+	Use:         "status",
+	Short:       "Show current codestash context and scope",
+	Annotations: rendersDocuments(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		st := requireState()
 		ctx, err := st.Current()
 		if err != nil {
 			return err
 		}
-		scope := st.Scope()
-
-		cmd.Printf("Context: %s (collection: %s, folder: %s)\n", ctx.Name, ctx.Collection, ctx.Folder)
-		cmd.Printf("Scope: %s\n", scope)
-
-		if scope == state.ScopeNote {
+		doc := statusDoc{
+			Context:    ctx.Name,
+			Collection: ctx.Collection,
+			Folder:     ctx.Folder,
+			Scope:      string(st.Scope()),
+		}
+		if cfg, err := loadConfig(); err == nil && cfg.Profile != "" {
+			doc.Profile = cfg.Profile
+			doc.APIBaseURL = cfg.APIBaseURL
+		}
+		if st.Scope() == state.ScopeNote {
 			noteID, noteTitle, err := st.CurrentNote()
 			if err != nil {
 				return err
 			}
-			if noteTitle != "" {
-				cmd.Printf("Note: %s (%s)\n", noteTitle, noteID)
-			} else {
-				cmd.Printf("Note: %s\n", noteID)
-			}
-			cmd.Println("Available commands: notes update, note exit, notes list, status")
-		} else {
-			cmd.Println("Note: <none>")
-			cmd.Println("Available commands: notes create, notes list, note switch, context switch, status")
+			doc.Note = &statusNote{ID: noteID, Title: noteTitle}
 		}
 
-		return nil
+		return render(cmd, doc, func() error {
+			cmd.Printf("Context: %s (collection: %s, folder: %s)\n", doc.Context, doc.Collection, doc.Folder)
+			if doc.Profile != "" {
+				cmd.Printf("Profile: %s (%s)\n", doc.Profile, doc.APIBaseURL)
+			}
+			cmd.Printf("Scope: %s\n", doc.Scope)
+
+			if doc.Note != nil {
+				if doc.Note.Title != "" {
+					cmd.Printf("Note: %s (%s)\n", doc.Note.Title, doc.Note.ID)
+				} else {
+					cmd.Printf("Note: %s\n", doc.Note.ID)
+				}
+				cmd.Println("Available commands: notes show, notes diff, notes update, note exit, notes list, status")
+			} else {
+				cmd.Println("Note: <none>")
+				cmd.Println("Available commands: notes create, notes list, notes show, notes diff, notes delete, search, note switch, context switch, status")
+			}
+			return nil
+		})
 	},
 }
 
+// statusDoc is the --output document of `status`.
+type statusDoc struct {
+	Context    string      `json:"context"`
+	Collection string      `json:"collection"`
+	Folder     string      `json:"folder"`
+	Profile    string      `json:"profile,omitempty"`
+	APIBaseURL string      `json:"api_base_url,omitempty"`
+	Scope      string      `json:"scope"`
+	Note       *statusNote `json:"note"`
+}
+
+type statusNote struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 }
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+	"github.com/k-kanke/code-stash-cli/internal/fsutil"
+	"github.com/k-kanke/code-stash-cli/internal/state"
+)
+
+var syncDryRun bool
+
+type syncAction string
+
+const (
+	syncUpToDate syncAction = "ok"
+	syncPush     syncAction = "push"
+	syncPull     syncAction = "pull"
+	syncConflict syncAction = "conflict"
+	syncSkip     syncAction = "skip"
+	syncFailed   syncAction = "error"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Push and pull every mapped file in the current context",
+	Long: `Compare every file mapped in the current context with its note.
+
+Files changed only locally are pushed, notes changed only remotely are
+pulled, and files changed on both sides are reported as conflicts and left
+alone. A file that cannot be synced is reported and the others are still
+synced. Use --dry-run to see the plan without changing anything.`,
+	SilenceUsage: true,
+	Annotations:  rendersDocuments(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st := requireState()
+		ctx, err := st.Current()
+		if err != nil {
+			return err
+		}
+		doc := syncDoc{DryRun: syncDryRun, Files: []syncFileDoc{}}
+		paths := st.FileMappings(ctx.Name)
+		if len(paths) == 0 {
+			return render(cmd, doc, func() error {
+				cmd.Println("No mapped files in this context.")
+				return nil
+			})
+		}
+
+		sess, err := newSession()
+		if err != nil {
+			return err
+		}
+
+		// A failure on one file does not stop the others, and what was
+		// synced before it is still saved below.
+		conflicts, failures := 0, 0
+		report := func(status mappingStatus, action syncAction, reason string) {
+			doc.Files = append(doc.Files, syncFileDoc{Path: status.path, NoteID: status.mapping.NoteID, Action: string(action), Reason: reason})
+			if outFormat.IsText() {
+				printSyncItem(cmd, status, action, reason)
+			}
+		}
+		for _, rel := range paths {
+			m, _ := st.GetFileMapping(ctx.Name, rel)
+			status, err := inspectMapping(cmd, sess, rel, m)
+			if err != nil {
+				failures++
+				report(status, syncFailed, err.Error())
+				continue
+			}
+			action, reason := syncPlan(status)
+			if action == syncConflict {
+				conflicts++
+			}
+			if syncDryRun || action == syncConflict || action == syncSkip {
+				report(status, action, reason)
+				continue
+			}
+
+			err = applySync(cmd, sess, st, ctx.Name, status, action)
+			if api.IsEditConflict(err) {
+				// The note changed between inspecting and pushing.
+				conflicts++
+				report(status, syncConflict, "changed remotely during sync")
+				continue
+			}
+			if err != nil {
+				failures++
+				report(status, syncFailed, err.Error())
+				continue
+			}
+			report(status, action, reason)
+		}
+
+		if !syncDryRun {
+			if err := st.Save(); err != nil {
+				return err
+			}
+		}
+		// The text output was printed file by file above.
+		if err := render(cmd, doc, func() error { return nil }); err != nil {
+			return err
+		}
+		var problems []string
+		if failures > 0 {
+			problems = append(problems, fmt.Sprintf("%d file(s) failed to sync", failures))
+		}
+		if conflicts > 0 {
+			problems = append(problems, fmt.Sprintf("%d file(s) changed both locally and remotely; resolve them and sync again", conflicts))
+		}
+		if len(problems) > 0 {
+			return errors.New(strings.Join(problems, "; "))
+		}
+		return nil
+	},
+}
+
+// syncDoc is the --output document of `sync`.
+type syncDoc struct {
+	DryRun bool          `json:"dry_run"`
+	Files  []syncFileDoc `json:"files"`
+}
+
+// syncFileDoc is a mapped file in syncDoc. Action is ok, push, pull,
+// conflict, skip or error; with --dry-run it is the planned action.
+type syncFileDoc struct {
+	Path   string `json:"path"`
+	NoteID string `json:"note_id"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "show what would be pushed and pulled without doing it")
+}
+
+// syncPlan maps a file's status to the sync action and an optional reason.
+func syncPlan(status mappingStatus) (action syncAction, reason string) {
+	switch status.state {
+	case stateModified:
+		return syncPush, ""
+	case stateRemoteChanged:
+		return syncPull, ""
+	case stateConflict:
+		return syncConflict, "changed locally and remotely"
+	case stateMissing:
+		return syncSkip, "missing locally; run `codestash notes pull " + status.mapping.NoteID + "` to restore it"
+	case stateDeleted:
+		return syncSkip, "note " + status.mapping.NoteID + " no longer exists"
+	}
+	return syncUpToDate, ""
+}
+
+// applySync carries out action and records the file as synced.
+func applySync(cmd *cobra.Command, sess *session, st *state.State, ctxName string, status mappingStatus, action syncAction) error {
+	var err error
+	switch action {
+	case syncPush:
+		code := string(status.local)
+		req := api.UpdateNoteRequest{Code: &code}
+		updated, err := sess.client.UpdateNote(cmd.Context(), status.mapping.NoteID, req, mappingPrecondition(status.mapping))
+		if err != nil {
+			return err
+		}
+		_, err = recordSynced(cmd, sess, st, ctxName, status.path, status.mapping.NoteID, status.local, updated)
+		return err
+	case syncPull:
+		abs := filepath.Join(projectRoot, filepath.FromSlash(status.path))
+		code := []byte(status.note.Code)
+		if err := fsutil.WriteFileAtomic(abs, code, 0o644); err != nil {
+			return err
+		}
+		_, err = recordSynced(cmd, sess, st, ctxName, status.path, status.mapping.NoteID, code, status.note)
+	default:
+		_, err = recordSynced(cmd, sess, st, ctxName, status.path, status.mapping.NoteID, status.local, status.note)
+	}
+	return err
+}
+
+func printSyncItem(cmd *cobra.Command, status mappingStatus, action syncAction, reason string) {
+	line := fmt.Sprintf("%-8s  %s -> %s", action, status.path, status.mapping.NoteID)
+	if reason != "" {
+		line += " (" + reason + ")"
+	}
+	cmd.Println(line)
+}
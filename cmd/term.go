@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor reports whether ANSI colors should be written to w.
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// terminalWidth returns the width of the terminal w writes to, or 0 when w
+// is not a terminal. $COLUMNS overrides it, as in most shells.
+func terminalWidth(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return 0
+	}
+	return ttyWidth(f)
+}
+
+// confirm asks a yes/no question on cmd's input; anything but yes is no.
+func confirm(cmd *cobra.Command, question string) bool {
+	promptf(cmd, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// promptf prints a question or other part of an interactive exchange. It
+// goes to stderr when stdout carries an --output document.
+func promptf(cmd *cobra.Command, format string, args ...any) {
+	if outFormat.IsText() {
+		cmd.Printf(format, args...)
+	} else {
+		cmd.PrintErrf(format, args...)
+	}
+}
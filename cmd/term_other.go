@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package cmd
+
+import "os"
+
+func ttyWidth(f *os.File) int {
+	return 0
+}
@@ -3,78 +3,85 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type DeviceCodeResponse struct {
-	DeviceCode              string `json:"device_code"`
-	UserCode                string `json:"user_code"`
-	VerificationURI         string `json:"verification_uri"`
-	VerificationURIComplete string `json:"verification_uri_complete"`
-	ExpiresIn               int    `json:"expires_in"`
-	Interval                int    `json:"interval"`
-}
+// DefaultUserAgent is sent unless overridden with WithUserAgent.
+const DefaultUserAgent = "codestash-cli"
 
-type TokenResponse struct {
-	AccessToken  string  `json:"access_token"`
-	TokenType    string  `json:"token_type"`
-	ExpiresIn    int     `json:"expires_in"`
-	RefreshToken *string `json:"refresh_token"`
-	Scope        string  `json:"scope"`
+type Client struct {
+	baseURL      *url.URL
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	retry      RetryPolicy
+	timeout    time.Duration
+	userAgent  string
+	tokens     TokenSource
+	debug      io.Writer
+	middleware []Middleware
 }
 
-type APIError struct {
-	Code        string `json:"error"`
-	Description string `json:"error_description"`
+type Option func(*Client)
+
+// WithRetryPolicy sets how idempotent requests are retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
 }
 
-type CreateNoteRequest struct {
-	CollectionID string   `json:"collection_id"`
-	FolderID     string   `json:"folder_id"`
-	Title        string   `json:"title"`
-	Language     string   `json:"language"`
-	Tags         []string `json:"tags"`
-	Code         string   `json:"code"`
-	Note         string   `json:"note"`
+// WithTimeout sets the per-attempt HTTP timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.timeout = d
+		}
+	}
 }
 
-type CreateNoteResponse struct {
-	NoteID string `json:"id"`
+// WithTokenSource authenticates API requests with tokens from ts.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = ts
+	}
 }
 
-type NoteSummary struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Language  string    `json:"language"`
-	Tags      []string  `json:"tags"`
-	Snippet   string    `json:"snippet"`
-	FolderID  *string   `json:"folder_id"`
-	UpdatedAt time.Time `json:"updated_at"`
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		if ua != "" {
+			c.userAgent = ua
+		}
+	}
 }
 
-type UpdateNoteRequest struct {
-	Title    *string  `json:"title,omitempty"`
-	Language *string  `json:"language,omitempty"`
-	Tags     []string `json:"tags,omitempty"`
-	Code     *string  `json:"code,omitempty"`
-	Note     *string  `json:"note,omitempty"`
+// WithDebug logs every HTTP attempt to w.
+func WithDebug(w io.Writer) Option {
+	return func(c *Client) {
+		c.debug = w
+	}
 }
 
-type Client struct {
-	baseURL      *url.URL
-	clientID     string
-	clientSecret string
-	httpClient   *http.Client
+// WithMiddleware adds middleware outside the built-in ones.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mws...)
+	}
 }
 
-func NewClient(baseURL, clientID, clientSecret string) (*Client, error) {
+func NewClient(baseURL, clientID, clientSecret string, opts ...Option) (*Client, error) {
 	if strings.TrimSpace(baseURL) == "" {
 		return nil, fmt.Errorf("api base url is required")
 	}
@@ -83,111 +90,111 @@ func NewClient(baseURL, clientID, clientSecret string) (*Client, error) {
 		return nil, fmt.Errorf("invalid api base url: %w", err)
 	}
 
-	return &Client{
+	c := &Client{
 		baseURL:      parsed,
 		clientID:     clientID,
 		clientSecret: clientSecret,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
-	}, nil
-}
-
-func (c *Client) StartDeviceCode(ctx context.Context) (*DeviceCodeResponse, error) {
-	payload := map[string]string{
-		"client_id": c.clientID,
+		retry:        DefaultRetryPolicy(),
+		timeout:      15 * time.Second,
+		userAgent:    DefaultUserAgent,
 	}
-	var resp DeviceCodeResponse
-	if err := c.post(ctx, "/oauth/device/code", payload, &resp); err != nil {
-		return nil, err
+	for _, opt := range opts {
+		opt(c)
 	}
-	return &resp, nil
-}
 
-func (c *Client) ExchangeDeviceCode(ctx context.Context, deviceCode string) (*TokenResponse, *APIError, error) {
-	payload := map[string]string{
-		"grant_type":    "device_code",
-		"device_code":   deviceCode,
-		"client_id":     c.clientID,
-		"client_secret": c.clientSecret,
-	}
-
-	req, err := c.newRequest(ctx, "POST", "/oauth/token", payload)
-	if err != nil {
-		return nil, nil, err
+	// Outermost first: auth sits outside retries so a refreshed token is
+	// used for every retry, and logging sees each individual attempt.
+	mws := append([]Middleware{}, c.middleware...)
+	mws = append(mws, UserAgent(c.userAgent), RequestID(), Auth(c.tokens), Retry(c.retry))
+	if c.debug != nil {
+		mws = append(mws, Logging(c.debug))
 	}
+	mws = append(mws, Timeout(c.timeout))
+	c.httpClient = &http.Client{Transport: chain(http.DefaultTransport, mws...)}
+	return c, nil
+}
 
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer res.Body.Close()
+// request describes one API call for do.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+	// public requests skip the bearer token (OAuth endpoints).
+	public bool
+}
 
-	if res.StatusCode >= 200 && res.StatusCode < 300 {
-		var body TokenResponse
-		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
-			return nil, nil, fmt.Errorf("decode token response: %w", err)
+// do sends r through the middleware chain, turns non-2xx responses into
+// *Error and decodes a JSON body into out. An empty body leaves out
+// untouched. The returned response has its body closed and is only useful
+// for its status and headers.
+func (c *Client) do(ctx context.Context, r request, out any) (*http.Response, error) {
+	var body io.Reader
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			return nil, fmt.Errorf("encode payload: %w", err)
 		}
-		return &body, nil, nil
+		body = bytes.NewReader(data)
 	}
 
-	apiErr, err := decodeAPIError(res.Body)
-	if err != nil {
-		return nil, nil, err
+	u := c.cloneBaseURL()
+	u.Path = joinPath(u.Path, r.path)
+	if len(r.query) > 0 {
+		u.RawQuery = r.query.Encode()
 	}
-	return nil, apiErr, nil
-}
 
-func (c *Client) post(ctx context.Context, target string, payload any, out any) error {
-	req, err := c.newRequest(ctx, "POST", target, payload)
+	if r.public {
+		ctx = public(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for k, vs := range r.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
 	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
 
 	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		var tokenErr *tokenError
+		if errors.As(err, &tokenErr) {
+			return nil, tokenErr.err
+		}
+		return nil, err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		apiErr, err := decodeAPIError(res.Body)
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("api error: %s", apiErr.Code)
+	if err := checkResponse(res); err != nil {
+		return res, err
 	}
-
 	if out == nil {
-		return nil
+		return res, nil
 	}
-	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
-		return fmt.Errorf("decode response: %w", err)
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return res, fmt.Errorf("decode response: %w", err)
 	}
-	return nil
+	return res, nil
 }
 
-func (c *Client) newRequest(ctx context.Context, method, endpoint string, payload any) (*http.Request, error) {
-	var bodyReader io.Reader
-	if payload != nil {
-		body, err := json.Marshal(payload)
-		if err != nil {
-			return nil, fmt.Errorf("encode payload: %w", err)
-		}
-		bodyReader = bytes.NewReader(body)
-	}
-
-	u := *c.baseURL
-	u.Path = joinPath(c.baseURL.Path, endpoint)
+func (c *Client) cloneBaseURL() *url.URL {
+	clone := *c.baseURL
+	return &clone
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
-	if err != nil {
-		return nil, err
-	}
-	if payload != nil {
-		req.Header.Set("Content-Type", "application/json")
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random id: %w", err)
 	}
-	return req, nil
+	return hex.EncodeToString(buf), nil
 }
 
 func joinPath(basePath, endpoint string) string {
@@ -201,100 +208,255 @@ func joinPath(basePath, endpoint string) string {
 	return base + "/" + endpoint
 }
 
-func decodeAPIError(r io.Reader) (*APIError, error) {
-	var apiErr APIError
-	if err := json.NewDecoder(r).Decode(&apiErr); err != nil {
-		return nil, fmt.Errorf("decode api error: %w", err)
-	}
-	if apiErr.Code == "" {
-		apiErr.Code = "unknown_error"
+func (c *Client) StartDeviceCode(ctx context.Context) (*DeviceCodeResponse, error) {
+	var resp DeviceCodeResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/device/code",
+		body:   map[string]string{"client_id": c.clientID},
+		public: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
 	}
-	return &apiErr, nil
+	return &resp, nil
+}
+
+func (c *Client) ExchangeDeviceCode(ctx context.Context, deviceCode string) (*TokenResponse, *Error, error) {
+	return c.exchangeToken(ctx, map[string]string{
+		"grant_type":    "device_code",
+		"device_code":   deviceCode,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	})
 }
 
-func (c *Client) CreateNote(ctx context.Context, accessToken string, payload CreateNoteRequest) (*CreateNoteResponse, error) {
-	req, err := c.newRequest(ctx, "POST", "/api/collections/"+payload.CollectionID+"/notes", payload)
+// AuthorizeURL builds the browser URL for the authorization-code flow with
+// PKCE (RFC 7636, S256 method).
+func (c *Client) AuthorizeURL(redirectURI, state, codeChallenge string) string {
+	u := c.cloneBaseURL()
+	u.Path = joinPath(u.Path, "/oauth/authorize")
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (c *Client) ExchangeAuthorizationCode(ctx context.Context, code, redirectURI, codeVerifier string) (*TokenResponse, *Error, error) {
+	return c.exchangeToken(ctx, map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  redirectURI,
+		"code_verifier": codeVerifier,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	})
+}
+
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, *Error, error) {
+	return c.exchangeToken(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	})
+}
+
+// exchangeToken posts a grant to the token endpoint. OAuth errors such as
+// authorization_pending are returned as a separate *Error so callers can
+// react to them.
+func (c *Client) exchangeToken(ctx context.Context, payload map[string]string) (*TokenResponse, *Error, error) {
+	var resp TokenResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/token",
+		body:   payload,
+		public: true,
+	}, &resp)
 	if err != nil {
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			return nil, apiErr, nil
+		}
+		return nil, nil, err
+	}
+	return &resp, nil, nil
+}
+
+// RevokeToken asks the server to invalidate a token (RFC 7009).
+// tokenTypeHint is either "access_token" or "refresh_token".
+func (c *Client) RevokeToken(ctx context.Context, token, tokenTypeHint string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/revoke",
+		body: map[string]string{
+			"token":           token,
+			"token_type_hint": tokenTypeHint,
+			"client_id":       c.clientID,
+			"client_secret":   c.clientSecret,
+		},
+		public: true,
+	}, nil)
+	return err
+}
+
+func (c *Client) UserInfo(ctx context.Context) (*UserInfo, error) {
+	var info UserInfo
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/oauth/userinfo"}, &info); err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return &info, nil
+}
 
-	res, err := c.httpClient.Do(req)
+// CreateNote returns a nil response if the server replied without a body.
+func (c *Client) CreateNote(ctx context.Context, payload CreateNoteRequest) (*CreateNoteResponse, error) {
+	var resp *CreateNoteResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/collections/" + payload.CollectionID + "/notes",
+		body:   payload,
+	}, &resp)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
+	return resp, nil
+}
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		apiErr, err := decodeAPIError(res.Body)
+// ListNotes returns all notes in a collection that match filter. Use Notes
+// to stream large collections instead.
+func (c *Client) ListNotes(ctx context.Context, collectionID string, filter NoteFilter) ([]NoteSummary, error) {
+	var notes []NoteSummary
+	for n, err := range c.Notes(ctx, collectionID, filter, 0) {
 		if err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("api error: %s", apiErr.Code)
+		notes = append(notes, n)
 	}
+	return notes, nil
+}
 
-	var resp CreateNoteResponse
-	dec := json.NewDecoder(res.Body)
-	if err := dec.Decode(&resp); err != nil {
-		if errors.Is(err, io.EOF) {
-			return nil, nil
+// Notes iterates over the notes in a collection that match filter, fetching
+// pages of pageSize notes as the loop advances; 0 leaves the page size to
+// the server. The filter is sent as query parameters and applied again to
+// each page, since not every server supports all of them (see
+// NoteFilter.Match). Iteration stops after the first error.
+func (c *Client) Notes(ctx context.Context, collectionID string, filter NoteFilter, pageSize int) iter.Seq2[NoteSummary, error] {
+	return func(yield func(NoteSummary, error) bool) {
+		cursor := ""
+		for {
+			page, err := c.notesPage(ctx, collectionID, filter, pageSize, cursor)
+			if err != nil {
+				yield(NoteSummary{}, err)
+				return
+			}
+			for _, n := range page.Items {
+				if filter.Match(n) && !yield(n, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			if page.NextCursor == cursor {
+				yield(NoteSummary{}, fmt.Errorf("list notes: server returned cursor %q again", cursor))
+				return
+			}
+			cursor = page.NextCursor
 		}
-		return nil, fmt.Errorf("decode response: %w", err)
 	}
-	return &resp, nil
 }
 
-func (c *Client) ListNotes(ctx context.Context, accessToken, collectionID string) ([]NoteSummary, error) {
-	req, err := c.newRequest(ctx, "GET", "/api/collections/"+collectionID+"/notes", nil)
-	if err != nil {
-		return nil, err
+func (c *Client) notesPage(ctx context.Context, collectionID string, filter NoteFilter, pageSize int, cursor string) (*notePage, error) {
+	query := filter.values()
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	res, err := c.httpClient.Do(req)
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	var page notePage
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/collections/" + collectionID + "/notes",
+		query:  query,
+	}, &page)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
+	return &page, nil
+}
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		apiErr, err := decodeAPIError(res.Body)
-		if err != nil {
-			return nil, err
-		}
-		return nil, fmt.Errorf("api error: %s", apiErr.Code)
+// Search runs a full-text search over notes.
+func (c *Client) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	query := url.Values{"q": {req.Query}}
+	if req.CollectionID != "" {
+		query.Set("collection_id", req.CollectionID)
 	}
-
-	var notes []NoteSummary
-	if err := json.NewDecoder(res.Body).Decode(&notes); err != nil {
-		if errors.Is(err, io.EOF) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("decode response: %w", err)
+	if req.FolderID != "" {
+		query.Set("folder_id", req.FolderID)
 	}
-	return notes, nil
+	for _, f := range req.Fields {
+		query.Add("field", f)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	var resp struct {
+		Results []SearchResult `json:"results"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/search", query: query}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
 }
 
-func (c *Client) UpdateNote(ctx context.Context, accessToken, noteID string, payload UpdateNoteRequest) error {
-	req, err := c.newRequest(ctx, "PATCH", "/api/note/"+noteID, payload)
+// UpdateNote patches a note and returns the updated note, or nil if the
+// server replied without a body. Unless pre is zero, the server rejects the
+// update with 409 or 412 (see IsEditConflict) when the note has moved on.
+func (c *Client) UpdateNote(ctx context.Context, noteID string, payload UpdateNoteRequest, pre Precondition) (*Note, error) {
+	// The same key on every attempt lets the server deduplicate retries.
+	key, err := randomHex(16)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	res, err := c.httpClient.Do(req)
+	header := http.Header{"Idempotency-Key": {key}}
+	pre.apply(header)
+	var note *Note
+	res, err := c.do(ctx, request{
+		method: http.MethodPatch,
+		path:   "/api/note/" + noteID,
+		header: header,
+		body:   payload,
+	}, &note)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer res.Body.Close()
-
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		apiErr, err := decodeAPIError(res.Body)
-		if err != nil {
-			return err
+	if note != nil {
+		if etag := res.Header.Get("ETag"); etag != "" {
+			note.Revision = etag
 		}
-		return fmt.Errorf("api error: %s", apiErr.Code)
 	}
+	return note, nil
+}
+
+func (c *Client) DeleteNote(ctx context.Context, noteID string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/note/" + noteID}, nil)
+	return err
+}
 
-	return nil
+func (c *Client) GetNote(ctx context.Context, noteID string) (*Note, error) {
+	var note Note
+	res, err := c.do(ctx, request{method: http.MethodGet, path: "/api/note/" + noteID}, &note)
+	if err != nil {
+		return nil, err
+	}
+	if etag := res.Header.Get("ETag"); etag != "" {
+		note.Revision = etag
+	}
+	return &note, nil
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody caps how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// requestIDHeaders are checked in order for a server-assigned request ID.
+var requestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id", "X-Amzn-Requestid"}
+
+// Error describes a non-2xx response from the API.
+type Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+	RequestID   string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("api error: ")
+	b.WriteString(e.Code)
+	if e.Description != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Description)
+	}
+	fmt.Fprintf(&b, " (HTTP %d", e.StatusCode)
+	if e.RequestID != "" {
+		b.WriteString(", request ID ")
+		b.WriteString(e.RequestID)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+func IsPreconditionFailed(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed)
+}
+
+// IsEditConflict reports whether a conditional write was rejected because
+// the resource changed. Servers answer with either 409 or 412.
+func IsEditConflict(err error) bool {
+	return IsConflict(err) || IsPreconditionFailed(err)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// checkResponse returns an *Error for non-2xx responses.
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	return newError(res)
+}
+
+// newError builds an *Error from a failed response. Bodies that are not the
+// usual OAuth-style JSON (e.g. an HTML page from a proxy) are tolerated.
+func newError(res *http.Response) *Error {
+	apiErr := &Error{StatusCode: res.StatusCode}
+	for _, h := range requestIDHeaders {
+		if id := res.Header.Get(h); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	var payload struct {
+		Code        string `json:"error"`
+		Description string `json:"error_description"`
+		Message     string `json:"message"`
+		RequestID   string `json:"request_id"`
+	}
+	decoded := json.Unmarshal(body, &payload) == nil
+	if decoded {
+		apiErr.Code = payload.Code
+		apiErr.Description = payload.Description
+		if apiErr.Description == "" {
+			apiErr.Description = payload.Message
+		}
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = payload.RequestID
+		}
+	}
+
+	if apiErr.Code == "" {
+		apiErr.Code = statusCode(res.StatusCode)
+	}
+	// Only a body that is not JSON can serve as the description; servers do
+	// not always label their JSON with a Content-Type.
+	if !decoded && !isJSON(res) {
+		if text := strings.TrimSpace(string(body)); text != "" && !strings.HasPrefix(text, "<") && len(text) <= 200 {
+			apiErr.Description = text
+		}
+	}
+	return apiErr
+}
+
+// statusCode turns an HTTP status into a snake_case code such as
+// "bad_gateway" for responses without an error code.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "unknown_error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+func isJSON(res *http.Response) bool {
+	return strings.Contains(res.Header.Get("Content-Type"), "json")
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func response(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestNewError(t *testing.T) {
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+	tests := []struct {
+		name string
+		res  *http.Response
+		want Error
+	}{
+		{
+			name: "oauth style",
+			res:  response(400, jsonHeader, `{"error":"invalid_grant","error_description":"code expired"}`),
+			want: Error{StatusCode: 400, Code: "invalid_grant", Description: "code expired"},
+		},
+		{
+			name: "message instead of description",
+			res:  response(422, jsonHeader, `{"error":"invalid","message":"title is required"}`),
+			want: Error{StatusCode: 422, Code: "invalid", Description: "title is required"},
+		},
+		{
+			name: "request ID header",
+			res:  response(404, http.Header{"X-Request-Id": {"req-1"}, "Content-Type": {"application/json"}}, `{"error":"not_found","request_id":"body-id"}`),
+			want: Error{StatusCode: 404, Code: "not_found", RequestID: "req-1"},
+		},
+		{
+			name: "correlation ID header",
+			res:  response(500, http.Header{"X-Correlation-Id": {"corr-1"}}, ""),
+			want: Error{StatusCode: 500, Code: "internal_server_error", RequestID: "corr-1"},
+		},
+		{
+			name: "request ID in body",
+			res:  response(409, jsonHeader, `{"error":"conflict","request_id":"body-id"}`),
+			want: Error{StatusCode: 409, Code: "conflict", RequestID: "body-id"},
+		},
+		{
+			name: "json without a content type",
+			res:  response(401, nil, `{"error":"invalid_token"}`),
+			want: Error{StatusCode: 401, Code: "invalid_token"},
+		},
+		{
+			name: "json without a code",
+			res:  response(500, http.Header{"Content-Type": {"text/plain"}}, `{"detail":"boom"}`),
+			want: Error{StatusCode: 500, Code: "internal_server_error"},
+		},
+		{
+			name: "html from a proxy",
+			res:  response(502, http.Header{"Content-Type": {"text/html"}}, "<html><body>Bad Gateway</body></html>"),
+			want: Error{StatusCode: 502, Code: "bad_gateway"},
+		},
+		{
+			name: "plain text",
+			res:  response(503, http.Header{"Content-Type": {"text/plain"}}, "upstream connect error\n"),
+			want: Error{StatusCode: 503, Code: "service_unavailable", Description: "upstream connect error"},
+		},
+		{
+			name: "long plain text",
+			res:  response(500, nil, strings.Repeat("x", 201)),
+			want: Error{StatusCode: 500, Code: "internal_server_error"},
+		},
+		{
+			name: "invalid json",
+			res:  response(400, jsonHeader, `{"error":`),
+			want: Error{StatusCode: 400, Code: "bad_request"},
+		},
+		{
+			name: "unknown status",
+			res:  response(599, nil, ""),
+			want: Error{StatusCode: 599, Code: "unknown_error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newError(tt.res); *got != tt.want {
+				t.Errorf("newError = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  Error
+		want string
+	}{
+		{Error{StatusCode: 404, Code: "not_found"}, "api error: not_found (HTTP 404)"},
+		{
+			Error{StatusCode: 401, Code: "invalid_token", Description: "token expired", RequestID: "req-1"},
+			"api error: invalid_token: token expired (HTTP 401, request ID req-1)",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	wrapped := func(status int) error {
+		return fmt.Errorf("get note: %w", &Error{StatusCode: status, Code: "x"})
+	}
+	tests := []struct {
+		name string
+		is   func(error) bool
+		err  error
+		want bool
+	}{
+		{"not found", IsNotFound, wrapped(404), true},
+		{"not found, other status", IsNotFound, wrapped(500), false},
+		{"unauthorized", IsUnauthorized, wrapped(401), true},
+		{"conflict", IsConflict, wrapped(409), true},
+		{"precondition failed", IsPreconditionFailed, wrapped(412), true},
+		{"edit conflict on 409", IsEditConflict, wrapped(409), true},
+		{"edit conflict on 412", IsEditConflict, wrapped(412), true},
+		{"edit conflict on 400", IsEditConflict, wrapped(400), false},
+		{"plain error", IsNotFound, errors.New("not found"), false},
+		{"nil", IsNotFound, nil, false},
+	}
+	for _, tt := range tests {
+		if got := tt.is(tt.err); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	if err := checkResponse(response(204, nil, "")); err != nil {
+		t.Errorf("checkResponse(204) = %v, want nil", err)
+	}
+	if err := checkResponse(response(404, nil, "")); !IsNotFound(err) {
+		t.Errorf("checkResponse(404) = %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// NoteFilter narrows ListNotes. Zero fields match every note.
+type NoteFilter struct {
+	FolderID string
+	// Tags must all be present on a note.
+	Tags     []string
+	Language string
+	// UpdatedSince matches notes updated at or after it.
+	UpdatedSince time.Time
+	// Query is free text. Every whitespace-separated term must appear,
+	// ignoring case, in the title, a tag or the snippet.
+	Query string
+}
+
+func (f NoteFilter) values() url.Values {
+	q := url.Values{}
+	if f.FolderID != "" {
+		q.Set("folder_id", f.FolderID)
+	}
+	for _, tag := range f.Tags {
+		q.Add("tag", tag)
+	}
+	if f.Language != "" {
+		q.Set("language", f.Language)
+	}
+	if !f.UpdatedSince.IsZero() {
+		q.Set("updated_since", f.UpdatedSince.UTC().Format(time.RFC3339))
+	}
+	if f.Query != "" {
+		q.Set("q", f.Query)
+	}
+	return q
+}
+
+// Match reports whether n satisfies the filter. A note that the server
+// matched on code or a description beyond its snippet does not match
+// Query here, since a summary does not carry them.
+func (f NoteFilter) Match(n NoteSummary) bool {
+	if f.FolderID != "" && (n.FolderID == nil || *n.FolderID != f.FolderID) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.ContainsFunc(n.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			return false
+		}
+	}
+	if f.Language != "" && !strings.EqualFold(n.Language, f.Language) {
+		return false
+	}
+	if !f.UpdatedSince.IsZero() && n.UpdatedAt.Before(f.UpdatedSince) {
+		return false
+	}
+	for _, term := range strings.Fields(strings.ToLower(f.Query)) {
+		if !summaryContains(n, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// summaryContains reports whether the title, a tag or the snippet of n
+// contains term, which must be lower case.
+func summaryContains(n NoteSummary, term string) bool {
+	if strings.Contains(strings.ToLower(n.Title), term) || strings.Contains(strings.ToLower(n.Snippet), term) {
+		return true
+	}
+	return slices.ContainsFunc(n.Tags, func(t string) bool { return strings.Contains(strings.ToLower(t), term) })
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNoteFilterValues(t *testing.T) {
+	since := time.Date(2026, 3, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	tests := []struct {
+		name   string
+		filter NoteFilter
+		want   url.Values
+	}{
+		{"empty", NoteFilter{}, url.Values{}},
+		{
+			"all fields",
+			NoteFilter{FolderID: "f1", Tags: []string{"http", "go"}, Language: "go", UpdatedSince: since, Query: "retry"},
+			url.Values{
+				"folder_id":     {"f1"},
+				"tag":           {"http", "go"},
+				"language":      {"go"},
+				"updated_since": {"2026-03-01T00:00:00Z"},
+				"q":             {"retry"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.values(); got.Encode() != tt.want.Encode() {
+			t.Errorf("%s: values() = %q, want %q", tt.name, got.Encode(), tt.want.Encode())
+		}
+	}
+}
+
+func TestNoteFilterMatch(t *testing.T) {
+	folder := "f1"
+	updated := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	note := NoteSummary{
+		ID:        "n1",
+		Title:     "HTTP retries",
+		Language:  "go",
+		Tags:      []string{"http", "Retries"},
+		Snippet:   "func Do(req) { // exponential Backoff",
+		FolderID:  &folder,
+		UpdatedAt: updated,
+	}
+	tests := []struct {
+		name   string
+		filter NoteFilter
+		note   NoteSummary
+		want   bool
+	}{
+		{"empty filter", NoteFilter{}, note, true},
+		{"folder", NoteFilter{FolderID: "f1"}, note, true},
+		{"other folder", NoteFilter{FolderID: "f2"}, note, false},
+		{"folder on a note without one", NoteFilter{FolderID: "f1"}, NoteSummary{ID: "n2"}, false},
+		{"tag ignores case", NoteFilter{Tags: []string{"retries"}}, note, true},
+		{"all tags present", NoteFilter{Tags: []string{"http", "retries"}}, note, true},
+		{"one tag missing", NoteFilter{Tags: []string{"http", "grpc"}}, note, false},
+		{"language ignores case", NoteFilter{Language: "Go"}, note, true},
+		{"other language", NoteFilter{Language: "python"}, note, false},
+		{"updated at since", NoteFilter{UpdatedSince: updated}, note, true},
+		{"updated before since", NoteFilter{UpdatedSince: updated.Add(time.Second)}, note, false},
+		{"query in the title", NoteFilter{Query: "HTTP"}, note, true},
+		{"query in a tag", NoteFilter{Query: "retr"}, note, true},
+		{"query in the snippet", NoteFilter{Query: "backoff"}, note, true},
+		{"every query term matches somewhere", NoteFilter{Query: "  http  Backoff "}, note, true},
+		{"one query term missing", NoteFilter{Query: "http grpc"}, note, false},
+		{"query not in the summary", NoteFilter{Query: "not in the summary"}, note, false},
+		{"query with a failing field", NoteFilter{Query: "retries", Language: "rust"}, note, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.note); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
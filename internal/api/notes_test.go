@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNotePageUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantIDs   []string
+		wantNext  string
+		wantError bool
+	}{
+		{name: "envelope", data: `{"items":[{"id":"n1"},{"id":"n2"}],"next_cursor":"c2"}`, wantIDs: []string{"n1", "n2"}, wantNext: "c2"},
+		{name: "last page", data: `{"items":[{"id":"n3"}],"next_cursor":""}`, wantIDs: []string{"n3"}},
+		{name: "empty envelope", data: `{}`},
+		{name: "bare array", data: `[{"id":"n1"}]`, wantIDs: []string{"n1"}},
+		{name: "bare array with leading space", data: " \n[{\"id\":\"n1\"}]", wantIDs: []string{"n1"}},
+		{name: "empty array", data: `[]`},
+		{name: "invalid", data: `{"items":`, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page notePage
+			err := json.Unmarshal([]byte(tt.data), &page)
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Unmarshal succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := noteIDs(page.Items); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("items = %v, want %v", got, tt.wantIDs)
+			}
+			if page.NextCursor != tt.wantNext {
+				t.Errorf("next cursor = %q, want %q", page.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+// pagingServer serves notes n1..n<total> in pages, using the cursor as the
+// offset of the next page.
+func pagingServer(t *testing.T, total int, requests *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RawQuery)
+		q := r.URL.Query()
+		size, _ := strconv.Atoi(q.Get("page_size"))
+		if size == 0 {
+			size = 2
+		}
+		start, _ := strconv.Atoi(q.Get("cursor"))
+		end := min(start+size, total)
+
+		var page notePage
+		for i := start; i < end; i++ {
+			page.Items = append(page.Items, NoteSummary{ID: fmt.Sprintf("n%d", i+1), Language: []string{"go", "python"}[i%2]})
+		}
+		if end < total {
+			page.NextCursor = strconv.Itoa(end)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testClient(t *testing.T, baseURL string) *Client {
+	t.Helper()
+	c, err := NewClient(baseURL, "id", "secret",
+		WithTokenSource(StaticToken("token")),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNotesPages(t *testing.T) {
+	var requests []string
+	c := testClient(t, pagingServer(t, 5, &requests).URL)
+
+	var got []NoteSummary
+	for n, err := range c.Notes(context.Background(), "c1", NoteFilter{}, 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	if ids := noteIDs(got); !slices.Equal(ids, []string{"n1", "n2", "n3", "n4", "n5"}) {
+		t.Errorf("notes = %v", ids)
+	}
+	want := []string{"page_size=2", "cursor=2&page_size=2", "cursor=4&page_size=2"}
+	if !slices.Equal(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+}
+
+func TestNotesStopsEarly(t *testing.T) {
+	var requests []string
+	c := testClient(t, pagingServer(t, 10, &requests).URL)
+
+	n := 0
+	for _, err := range c.Notes(context.Background(), "c1", NoteFilter{}, 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n++; n == 3 {
+			break
+		}
+	}
+	if len(requests) != 2 {
+		t.Errorf("sent %d requests for 3 notes in pages of 2, want 2", len(requests))
+	}
+}
+
+func TestNotesFilter(t *testing.T) {
+	var requests []string
+	c := testClient(t, pagingServer(t, 4, &requests).URL)
+
+	// The fake server ignores the language, so the client applies it.
+	notes, err := c.ListNotes(context.Background(), "c1", NoteFilter{Language: "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := noteIDs(notes); !slices.Equal(ids, []string{"n2", "n4"}) {
+		t.Errorf("notes = %v, want [n2 n4]", ids)
+	}
+	for _, q := range requests {
+		if !strings.Contains(q, "language=python") {
+			t.Errorf("request %q does not send the language", q)
+		}
+	}
+}
+
+func TestNotesBareArray(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `[{"id":"n1"},{"id":"n2"}]`)
+	}))
+	defer srv.Close()
+
+	notes, err := testClient(t, srv.URL).ListNotes(context.Background(), "c1", NoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := noteIDs(notes); !slices.Equal(ids, []string{"n1", "n2"}) || requests != 1 {
+		t.Errorf("notes = %v after %d requests, want [n1 n2] after 1", ids, requests)
+	}
+}
+
+func TestNotesRepeatedCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items":[{"id":"n1"}],"next_cursor":"same"}`)
+	}))
+	defer srv.Close()
+
+	_, err := testClient(t, srv.URL).ListNotes(context.Background(), "c1", NoteFilter{})
+	if err == nil || !strings.Contains(err.Error(), "cursor") {
+		t.Errorf("err = %v, want a repeated cursor error", err)
+	}
+}
+
+func TestNotesError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := testClient(t, srv.URL).ListNotes(context.Background(), "c1", NoteFilter{})
+	if !IsNotFound(err) {
+		t.Errorf("err = %v, want a not found error", err)
+	}
+}
+
+func noteIDs(notes []NoteSummary) []string {
+	var ids []string
+	for _, n := range notes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
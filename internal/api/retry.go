@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfter is the longest Retry-After we are willing to wait; beyond
+// that the response is returned to the caller as is.
+const maxRetryAfter = time.Minute
+
+// RetryPolicy controls how failed idempotent requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// backoff returns a delay with full jitter for the given retry (0-based).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	limit := p.MaxDelay
+	if d := p.BaseDelay << retry; d > 0 && d < limit {
+		limit = d
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+// Retry resends idempotent requests that failed with a transient error,
+// waiting with exponential backoff or as long as Retry-After asks.
+func Retry(p RetryPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts := p.MaxAttempts
+			if attempts < 1 || !isIdempotent(req) {
+				attempts = 1
+			}
+
+			r := req
+			for attempt := 1; ; attempt++ {
+				res, err := next.RoundTrip(r)
+				if attempt >= attempts || !shouldRetry(req.Context(), res, err) {
+					return res, err
+				}
+
+				delay := p.backoff(attempt - 1)
+				if res != nil {
+					if after, ok := retryAfter(res); ok {
+						if after > maxRetryAfter {
+							return res, nil
+						}
+						delay = after
+					}
+					_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxErrorBody))
+					res.Body.Close()
+				}
+
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(delay):
+				}
+
+				r = req.Clone(req.Context())
+				if err := rewindBody(r, req); err != nil {
+					return nil, err
+				}
+			}
+		})
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		// Do not retry once the caller has given up.
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header in either seconds or HTTP-date form.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
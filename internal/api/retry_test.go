@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"missing", "", 0, false},
+		{"seconds", "3", 3 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, false},
+		{"garbage", "soon", 0, false},
+		{"past date", "Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		res := response(503, http.Header{"Retry-After": {tt.header}}, "")
+		got, ok := retryAfter(res)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: retryAfter = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	got, ok := retryAfter(response(429, http.Header{"Retry-After": {future}}, ""))
+	if !ok || got <= 20*time.Second || got > 30*time.Second {
+		t.Errorf("retryAfter(%q) = %v, %v; want about 30s", future, got, ok)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, limit := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for range 20 {
+			if d := p.backoff(retry); d < 0 || d >= limit {
+				t.Fatalf("backoff(%d) = %v, want in [0, %v)", retry, d, limit)
+			}
+		}
+	}
+	if d := (RetryPolicy{}).backoff(0); d != 0 {
+		t.Errorf("zero policy backoff = %v, want 0", d)
+	}
+}
+
+// attempt is one canned reply from a fake transport.
+type attempt struct {
+	status int
+	header http.Header
+	err    error
+}
+
+// fakeTransport replies with attempts in order and records request bodies.
+type fakeTransport struct {
+	attempts []attempt
+	bodies   []string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	f.bodies = append(f.bodies, body)
+	a := f.attempts[min(len(f.bodies), len(f.attempts))-1]
+	if a.err != nil {
+		return nil, a.err
+	}
+	return response(a.status, a.header, ""), nil
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	connErr := errors.New("connection reset")
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		attempts   []attempt
+		wantStatus int
+		wantErr    error
+		wantCalls  int
+	}{
+		{
+			name: "success", method: http.MethodGet,
+			attempts:   []attempt{{status: 200}},
+			wantStatus: 200, wantCalls: 1,
+		},
+		{
+			name: "transient then success", method: http.MethodGet,
+			attempts:   []attempt{{status: 503}, {status: 502}, {status: 200}},
+			wantStatus: 200, wantCalls: 3,
+		},
+		{
+			name: "gives up after max attempts", method: http.MethodGet,
+			attempts:   []attempt{{status: 504}},
+			wantStatus: 504, wantCalls: 3,
+		},
+		{
+			name: "client errors are not retried", method: http.MethodGet,
+			attempts:   []attempt{{status: 404}, {status: 200}},
+			wantStatus: 404, wantCalls: 1,
+		},
+		{
+			name: "connection error retried", method: http.MethodGet,
+			attempts:   []attempt{{err: connErr}, {status: 200}},
+			wantStatus: 200, wantCalls: 2,
+		},
+		{
+			name: "post is not retried", method: http.MethodPost,
+			attempts:   []attempt{{status: 503}, {status: 200}},
+			wantStatus: 503, wantCalls: 1,
+		},
+		{
+			name: "post with idempotency key is retried", method: http.MethodPost,
+			header:     http.Header{"Idempotency-Key": {"k"}},
+			attempts:   []attempt{{status: 503}, {status: 201}},
+			wantStatus: 201, wantCalls: 2,
+		},
+		{
+			name: "retry-after is honored", method: http.MethodGet,
+			attempts:   []attempt{{status: 429, header: http.Header{"Retry-After": {"0"}}}, {status: 200}},
+			wantStatus: 200, wantCalls: 2,
+		},
+		{
+			name: "long retry-after is returned", method: http.MethodGet,
+			attempts:   []attempt{{status: 429, header: http.Header{"Retry-After": {"3600"}}}, {status: 200}},
+			wantStatus: 429, wantCalls: 1,
+		},
+		{
+			name: "canceled request is not retried", method: http.MethodGet,
+			attempts:  []attempt{{err: context.Canceled}, {status: 200}},
+			wantErr:   context.Canceled,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeTransport{attempts: tt.attempts}
+			rt := Retry(policy)(fake)
+			req, err := http.NewRequest(tt.method, "http://example.test/api", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			res, err := rt.RoundTrip(req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if len(fake.bodies) != tt.wantCalls {
+				t.Errorf("sent %d requests, want %d", len(fake.bodies), tt.wantCalls)
+			}
+			for i, body := range fake.bodies {
+				if body != "payload" {
+					t.Errorf("attempt %d sent body %q, want the original body", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := &fakeTransport{attempts: []attempt{{status: 503}}}
+	rt := Retry(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		defer cancel()
+		return fake.RoundTrip(req)
+	}))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.test/api", nil)
+
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(fake.bodies) != 1 {
+		t.Errorf("sent %d requests, want 1", len(fake.bodies))
+	}
+}
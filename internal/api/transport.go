@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps a RoundTripper to add cross-cutting behavior to every
+// request the Client sends.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chain applies middleware so that the first one is the outermost.
+func chain(base http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	rt := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// TokenSource supplies bearer tokens for authenticated requests.
+type TokenSource interface {
+	AccessToken(ctx context.Context) (string, error)
+	// RefreshAccessToken is called once when the server rejects the
+	// current token. Return ErrNoRefresh if the token cannot be refreshed.
+	RefreshAccessToken(ctx context.Context) (string, error)
+}
+
+// ErrNoRefresh tells the auth middleware to hand the 401 back to the caller.
+var ErrNoRefresh = errors.New("token cannot be refreshed")
+
+// StaticToken is a TokenSource for a fixed token.
+type StaticToken string
+
+func (t StaticToken) AccessToken(context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t StaticToken) RefreshAccessToken(context.Context) (string, error) {
+	return "", ErrNoRefresh
+}
+
+// tokenError carries TokenSource failures through http.Client, which would
+// otherwise wrap them in a *url.Error.
+type tokenError struct {
+	err error
+}
+
+func (e *tokenError) Error() string { return e.err.Error() }
+func (e *tokenError) Unwrap() error { return e.err }
+
+// refreshError is a failed refresh after a 401. It keeps the 401 in the
+// chain so that IsUnauthorized and the request ID survive.
+type refreshError struct {
+	unauthorized *Error
+	err          error
+}
+
+func (e *refreshError) Error() string   { return e.err.Error() + ": " + e.unauthorized.Error() }
+func (e *refreshError) Unwrap() []error { return []error{e.unauthorized, e.err} }
+
+type publicKey struct{}
+
+// public marks requests that must not carry the bearer token, such as the
+// OAuth endpoints.
+func public(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicKey{}, true)
+}
+
+func isPublic(req *http.Request) bool {
+	v, _ := req.Context().Value(publicKey{}).(bool)
+	return v
+}
+
+// Auth injects the bearer token from ts and, when the server answers 401,
+// refreshes the token once and resends the request.
+func Auth(ts TokenSource) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if ts == nil || isPublic(req) || req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+
+			token, err := ts.AccessToken(req.Context())
+			if err != nil {
+				return nil, &tokenError{err}
+			}
+			res, err := next.RoundTrip(withBearer(req, token))
+			if err != nil || res.StatusCode != http.StatusUnauthorized {
+				return res, err
+			}
+
+			token, err = ts.RefreshAccessToken(req.Context())
+			if errors.Is(err, ErrNoRefresh) {
+				return res, nil
+			}
+			if err != nil {
+				unauthorized := newError(res)
+				res.Body.Close()
+				return nil, &tokenError{&refreshError{unauthorized: unauthorized, err: err}}
+			}
+			retry := withBearer(req, token)
+			if err := rewindBody(retry, req); err != nil {
+				res.Body.Close()
+				return nil, err
+			}
+			res.Body.Close()
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// rewindBody gives dst a fresh copy of src's body so it can be sent again.
+func rewindBody(dst, src *http.Request) error {
+	if src.Body == nil || src.GetBody == nil {
+		return nil
+	}
+	body, err := src.GetBody()
+	if err != nil {
+		return fmt.Errorf("rewind request body: %w", err)
+	}
+	dst.Body = body
+	return nil
+}
+
+// UserAgent sets the User-Agent header.
+func UserAgent(ua string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			r := req.Clone(req.Context())
+			r.Header.Set("User-Agent", ua)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// RequestID tags each request with an X-Request-Id so that client logs can
+// be correlated with server traces. Retries reuse the same ID.
+func RequestID() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Request-Id") != "" {
+				return next.RoundTrip(req)
+			}
+			id, err := randomHex(8)
+			if err != nil {
+				return nil, err
+			}
+			r := req.Clone(req.Context())
+			r.Header.Set("X-Request-Id", id)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// Logging writes one line per attempt to w.
+func Logging(w io.Writer) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			id := req.Header.Get("X-Request-Id")
+			if err != nil {
+				fmt.Fprintf(w, "%s %s -> error: %v (%s, request %s)\n", req.Method, req.URL.Redacted(), err, elapsed, id)
+			} else {
+				fmt.Fprintf(w, "%s %s -> %d (%s, request %s)\n", req.Method, req.URL.Redacted(), res.StatusCode, elapsed, id)
+			}
+			return res, err
+		})
+	}
+}
+
+// Timeout bounds each attempt, including reading the response body.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if d <= 0 {
+				return next.RoundTrip(req)
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			res, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+			return res, nil
+		})
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTokens hands out token until refreshed, then refreshed.
+type fakeTokens struct {
+	token      string
+	refreshed  string
+	refreshErr error
+	refreshes  int
+}
+
+func (f *fakeTokens) AccessToken(context.Context) (string, error) {
+	return f.token, nil
+}
+
+func (f *fakeTokens) RefreshAccessToken(context.Context) (string, error) {
+	f.refreshes++
+	if f.refreshErr != nil {
+		return "", f.refreshErr
+	}
+	f.token = f.refreshed
+	return f.token, nil
+}
+
+// seen is one request as the server received it.
+type seen struct {
+	method, body               string
+	auth, userAgent, requestID string
+}
+
+// recordingServer answers with the statuses in order, repeating the last
+// one, and records every request.
+func recordingServer(t *testing.T, statuses ...int) (*httptest.Server, func() []seen) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []seen
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, seen{
+			method:    r.Method,
+			body:      string(body),
+			auth:      r.Header.Get("Authorization"),
+			userAgent: r.Header.Get("User-Agent"),
+			requestID: r.Header.Get("X-Request-Id"),
+		})
+		status := statuses[min(len(requests), len(statuses))-1]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "server-req")
+		w.WriteHeader(status)
+		switch {
+		case status == http.StatusUnauthorized:
+			io.WriteString(w, `{"error":"invalid_token"}`)
+		case status >= 400:
+			io.WriteString(w, `{"error":"unavailable"}`)
+		default:
+			io.WriteString(w, `{"id":"n1","note_id":"n1","sub":"u1"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []seen {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]seen(nil), requests...)
+	}
+}
+
+func TestAuthRefreshReplaysBody(t *testing.T) {
+	srv, requests := recordingServer(t, http.StatusUnauthorized, http.StatusCreated)
+	tokens := &fakeTokens{token: "old-secret", refreshed: "new-secret"}
+	var log strings.Builder
+	c, err := NewClient(srv.URL, "id", "secret",
+		WithTokenSource(tokens),
+		WithUserAgent("codestash-test/1"),
+		WithDebug(&log),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.CreateNote(context.Background(), CreateNoteRequest{CollectionID: "c1", Title: "Retries", Code: "package main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.NoteID != "n1" {
+		t.Errorf("CreateNote = %+v, want note n1", resp)
+	}
+	if tokens.refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", tokens.refreshes)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("server saw %d requests, want 2", len(got))
+	}
+	first, replay := got[0], got[1]
+	if first.auth != "Bearer old-secret" || replay.auth != "Bearer new-secret" {
+		t.Errorf("authorization = %q then %q, want the old then the refreshed token", first.auth, replay.auth)
+	}
+	if replay.method != http.MethodPost || replay.body != first.body || !strings.Contains(replay.body, `"title":"Retries"`) {
+		t.Errorf("replay sent %s %q, want the original POST body %q", replay.method, replay.body, first.body)
+	}
+	// User-Agent and the request ID are set outside auth, so the replay
+	// carries the same ones.
+	for i, r := range got {
+		if r.userAgent != "codestash-test/1" {
+			t.Errorf("request %d has User-Agent %q", i+1, r.userAgent)
+		}
+		if r.requestID == "" || r.requestID != first.requestID {
+			t.Errorf("request %d has request ID %q, want %q on every attempt", i+1, r.requestID, first.requestID)
+		}
+	}
+
+	// Logging sits inside auth and sees both attempts, without the tokens.
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "-> 401") || !strings.Contains(lines[1], "-> 201") {
+		t.Errorf("debug log =\n%s\nwant a 401 line and a 201 line", log.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "POST "+srv.URL+"/api/collections/c1/notes -> ") || !strings.Contains(line, "request "+first.requestID) {
+			t.Errorf("debug log line %q does not name the request and its ID", line)
+		}
+	}
+	if strings.Contains(log.String(), "secret") || strings.Contains(log.String(), "Bearer") {
+		t.Errorf("debug log leaks the authorization header:\n%s", log.String())
+	}
+}
+
+func TestAuthRefreshFails(t *testing.T) {
+	srv, requests := recordingServer(t, http.StatusUnauthorized)
+	refreshErr := errors.New("refresh token: invalid_grant")
+	tokens := &fakeTokens{token: "old", refreshErr: refreshErr}
+	c, err := NewClient(srv.URL, "id", "secret", WithTokenSource(tokens), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.UserInfo(context.Background())
+	if !IsUnauthorized(err) {
+		t.Errorf("err = %v, want the original 401", err)
+	}
+	if !errors.Is(err, refreshErr) {
+		t.Errorf("err = %v, want the refresh error in the chain", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "invalid_token" || apiErr.RequestID != "server-req" {
+		t.Errorf("err = %v, want invalid_token with request ID server-req", err)
+	}
+	if n := len(requests()); n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+}
+
+func TestAuthWithoutRefresh(t *testing.T) {
+	srv, requests := recordingServer(t, http.StatusUnauthorized)
+	c, err := NewClient(srv.URL, "id", "secret", WithTokenSource(StaticToken("pat")), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.UserInfo(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("err = %v, want the 401 as an *Error", err)
+	}
+	if got := requests(); len(got) != 1 || got[0].auth != "Bearer pat" {
+		t.Errorf("server saw %+v, want one request with the static token", got)
+	}
+}
+
+func TestAuthSkipsPublicRequests(t *testing.T) {
+	srv, requests := recordingServer(t, http.StatusOK)
+	tokens := &fakeTokens{token: "secret"}
+	c, err := NewClient(srv.URL, "id", "secret", WithTokenSource(tokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.RefreshToken(context.Background(), "refresh"); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests(); len(got) != 1 || got[0].auth != "" {
+		t.Errorf("server saw %+v, want one token request without authorization", got)
+	}
+}
+
+func TestRetryKeepsRequestID(t *testing.T) {
+	srv, requests := recordingServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	var log strings.Builder
+	c, err := NewClient(srv.URL, "id", "secret",
+		WithTokenSource(StaticToken("pat")),
+		WithDebug(&log),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.UserInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()
+	if len(got) != 2 || got[0].requestID == "" || got[0].requestID != got[1].requestID {
+		t.Fatalf("server saw %+v, want two attempts with one request ID", got)
+	}
+	if got[0].userAgent != DefaultUserAgent {
+		t.Errorf("User-Agent = %q, want %q", got[0].userAgent, DefaultUserAgent)
+	}
+	if n := strings.Count(log.String(), "request "+got[0].requestID); n != 2 {
+		t.Errorf("debug log =\n%s\nwant one line per attempt", log.String())
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv, _ := recordingServer(t, http.StatusOK)
+	// Extra middleware is outermost, so it sees the request before the
+	// built-in headers are set.
+	var outer http.Header
+	c, err := NewClient(srv.URL, "id", "secret",
+		WithTokenSource(StaticToken("pat")),
+		WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				outer = req.Header.Clone()
+				return next.RoundTrip(req)
+			})
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UserInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"Authorization", "X-Request-Id", "User-Agent"} {
+		if outer.Get(h) != "" {
+			t.Errorf("outer middleware saw %s = %q, want it unset", h, outer.Get(h))
+		}
+	}
+}
@@ -1,8 +1,142 @@
 package api
 
-import "net/url"
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
 
-func (c *Client) cloneBaseURL() *url.URL {
-	clone := *c.baseURL
-	return &clone
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type TokenResponse struct {
+	AccessToken  string  `json:"access_token"`
+	TokenType    string  `json:"token_type"`
+	ExpiresIn    int     `json:"expires_in"`
+	RefreshToken *string `json:"refresh_token"`
+	Scope        string  `json:"scope"`
+}
+
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type CreateNoteRequest struct {
+	CollectionID string   `json:"collection_id"`
+	FolderID     string   `json:"folder_id"`
+	Title        string   `json:"title"`
+	Language     string   `json:"language"`
+	Tags         []string `json:"tags"`
+	Code         string   `json:"code"`
+	Note         string   `json:"note"`
+}
+
+type CreateNoteResponse struct {
+	NoteID string `json:"id"`
+}
+
+type NoteSummary struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Language  string    `json:"language"`
+	Tags      []string  `json:"tags"`
+	Snippet   string    `json:"snippet"`
+	FolderID  *string   `json:"folder_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// notePage is one page of a note listing. Servers without pagination send
+// a bare array, which decodes as a single, last page.
+type notePage struct {
+	Items      []NoteSummary `json:"items"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+func (p *notePage) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		*p = notePage{}
+		return json.Unmarshal(data, &p.Items)
+	}
+	type plain notePage
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+type UpdateNoteRequest struct {
+	Title    *string  `json:"title,omitempty"`
+	Language *string  `json:"language,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Code     *string  `json:"code,omitempty"`
+	Note     *string  `json:"note,omitempty"`
+}
+
+// Note is a full note as returned by GetNote.
+type Note struct {
+	ID           string    `json:"id"`
+	CollectionID string    `json:"collection_id"`
+	FolderID     *string   `json:"folder_id"`
+	Title        string    `json:"title"`
+	Language     string    `json:"language"`
+	Tags         []string  `json:"tags"`
+	Code         string    `json:"code"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Revision is the ETag the server sent with the note, if any.
+	Revision string `json:"revision,omitempty"`
+}
+
+// Precondition makes a write conditional on the version of a note the
+// caller last saw. Revision is preferred; UpdatedAt is used for servers
+// that send no ETag. The zero value makes the write unconditional.
+type Precondition struct {
+	Revision  string
+	UpdatedAt time.Time
+}
+
+func (p Precondition) apply(h http.Header) {
+	switch {
+	case p.Revision != "":
+		h.Set("If-Match", p.Revision)
+	case !p.UpdatedAt.IsZero():
+		h.Set("If-Unmodified-Since", p.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// SearchRequest is a full-text search. Empty scope fields search everything
+// the user can see; empty Fields searches title, code, note and tags.
+type SearchRequest struct {
+	Query        string
+	CollectionID string
+	FolderID     string
+	Fields       []string
+	Limit        int
+}
+
+// SearchResult is a note that matched a search.
+type SearchResult struct {
+	NoteID       string        `json:"note_id"`
+	Title        string        `json:"title"`
+	CollectionID string        `json:"collection_id"`
+	FolderID     *string       `json:"folder_id"`
+	Language     string        `json:"language"`
+	Tags         []string      `json:"tags"`
+	Matches      []SearchMatch `json:"matches"`
+}
+
+// SearchMatch is a matching line in one field of a note. Line is 1-based
+// and zero for single-line fields such as the title.
+type SearchMatch struct {
+	Field string `json:"field"`
+	Line  int    `json:"line,omitempty"`
+	Text  string `json:"text"`
 }
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens", "token.json.enc")
+	store := &EncryptedFileStore{Path: path, Passphrase: "correct horse"}
+
+	if got, err := store.Load(); err != nil || got != nil {
+		t.Fatalf("Load before Save = %v, %v; want nil, nil", got, err)
+	}
+	want := Token{AccessToken: "access", RefreshToken: "refresh", Scope: []string{"notes"}, ExpiresAt: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)}
+	if err := store.Save(want); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "access") || strings.Contains(string(data), "refresh") {
+		t.Errorf("token file contains the token in plaintext:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	got, err := (&EncryptedFileStore{Path: path, Passphrase: "correct horse"}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken ||
+		strings.Join(got.Scope, " ") != "notes" || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("Load = %+v, want %+v", *got, want)
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Load(); err != nil || got != nil {
+		t.Errorf("Load after Delete = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestEncryptedFileStoreLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.enc")
+	if err := (&EncryptedFileStore{Path: good, Passphrase: "secret"}).Save(Token{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+
+	// write stores a modified copy of the good file.
+	write := func(name string, edit func(f *encryptedFile)) string {
+		f := file
+		f.Ciphertext = append([]byte(nil), file.Ciphertext...)
+		edit(&f)
+		data, err := json.Marshal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	notJSON := filepath.Join(dir, "garbage.enc")
+	if err := os.WriteFile(notJSON, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		passphrase string
+		wantErr    string
+	}{
+		{"wrong passphrase", good, "wrong", "wrong passphrase or corrupted file"},
+		{"flipped ciphertext bit", write("flipped.enc", func(f *encryptedFile) { f.Ciphertext[0] ^= 1 }), "secret", "wrong passphrase or corrupted file"},
+		{"other salt", write("salt.enc", func(f *encryptedFile) { f.Salt = []byte("0123456789abcdef") }), "secret", "wrong passphrase or corrupted file"},
+		{"future version", write("version.enc", func(f *encryptedFile) { f.Version = 2 }), "secret", "unsupported token file version 2"},
+		{"not json", notJSON, "secret", "decode token file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&EncryptedFileStore{Path: tt.path, Passphrase: tt.passphrase}).Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load = %v, %v; want an error containing %q", got, err, tt.wantErr)
+			}
+		})
+	}
+}
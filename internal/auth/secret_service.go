@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const secretService = "codestash"
+
+// SecretServiceStore keeps the token in the desktop keyring through the
+// freedesktop Secret Service API, using the secret-tool helper from libsecret
+// to talk to D-Bus.
+type SecretServiceStore struct {
+	Account string
+}
+
+func (s *SecretServiceStore) Load() (*Token, error) {
+	out, err := s.run(nil, "lookup", "service", secretService, "account", s.Account)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(out)) == 0 {
+			// secret-tool exits non-zero when nothing matches.
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var token Token
+	if err := json.Unmarshal(out, &token); err != nil {
+		return nil, fmt.Errorf("decode token: %w", err)
+	}
+	return &token, nil
+}
+
+func (s *SecretServiceStore) Save(token Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("encode token: %w", err)
+	}
+	label := "codestash token for " + s.Account
+	if _, err := s.run(data, "store", "--label", label, "service", secretService, "account", s.Account); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SecretServiceStore) Delete() error {
+	if _, err := s.run(nil, "clear", "service", secretService, "account", s.Account); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *SecretServiceStore) Location() string {
+	return fmt.Sprintf("secret service (service=%s, account=%s)", secretService, s.Account)
+}
+
+func (s *SecretServiceStore) run(stdin []byte, args ...string) ([]byte, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, errors.New("secret-service token store requires secret-tool (libsecret-tools)")
+	}
+	c := exec.Command(path, args...)
+	if stdin != nil {
+		c.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("secret-tool %s: %s: %w", args[0], msg, err)
+		}
+		return out, fmt.Errorf("secret-tool %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	BackendFile          = "file"
+	BackendSecretService = "secret-service"
+	BackendEncryptedFile = "encrypted-file"
+)
+
+// Store persists the CLI token in some backend.
+type Store interface {
+	Load() (*Token, error)
+	Save(token Token) error
+	Delete() error
+	// Location describes where the token lives, for user-facing messages.
+	Location() string
+}
+
+type StoreOptions struct {
+	Backend    string
+	Path       string
+	Passphrase string
+	// Account distinguishes tokens in shared backends such as the keyring.
+	Account string
+}
+
+func NewStore(opts StoreOptions) (Store, error) {
+	switch strings.TrimSpace(opts.Backend) {
+	case "", BackendFile:
+		return &FileStore{Path: opts.Path}, nil
+	case BackendSecretService:
+		return &SecretServiceStore{Account: opts.Account}, nil
+	case BackendEncryptedFile:
+		if opts.Passphrase == "" {
+			return nil, errors.New("encrypted-file token store requires a passphrase (set token_passphrase or CODESTASH_TOKEN_PASSPHRASE)")
+		}
+		return &EncryptedFileStore{Path: opts.Path + ".enc", Passphrase: opts.Passphrase}, nil
+	default:
+		return nil, fmt.Errorf("unknown token store %q (want %s, %s or %s)", opts.Backend, BackendFile, BackendSecretService, BackendEncryptedFile)
+	}
+}
+
+// MigrateFile moves a plaintext token written by SaveToken into dst, unless
+// dst already holds a token. It reports whether a token was migrated.
+func MigrateFile(path string, dst Store) (bool, error) {
+	if fs, ok := dst.(*FileStore); ok && fs.Path == path {
+		return false, nil
+	}
+	// Check for the legacy file first: loading dst can be expensive, e.g.
+	// deriving the key of an encrypted file.
+	legacy, err := LoadToken(path)
+	if err != nil {
+		return false, err
+	}
+	if legacy == nil {
+		return false, nil
+	}
+	existing, err := dst.Load()
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return false, nil
+	}
+	if err := dst.Save(*legacy); err != nil {
+		return false, fmt.Errorf("migrate token: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return true, fmt.Errorf("remove plaintext token: %w", err)
+	}
+	return true, nil
+}
+
+// FileStore keeps the token as plaintext JSON.
+type FileStore struct {
+	Path string
+}
+
+func (s *FileStore) Load() (*Token, error) {
+	return LoadToken(s.Path)
+}
+
+func (s *FileStore) Save(token Token) error {
+	return SaveToken(s.Path, token)
+}
+
+func (s *FileStore) Delete() error {
+	return DeleteToken(s.Path)
+}
+
+func (s *FileStore) Location() string {
+	return s.Path
+}
+
+// EnvStore serves a token supplied through an environment variable, such as a
+// personal access token in CI. It is read-only and never refreshed.
+type EnvStore struct {
+	Variable string
+	Value    string
+}
+
+func (s *EnvStore) Load() (*Token, error) {
+	return &Token{AccessToken: s.Value}, nil
+}
+
+func (s *EnvStore) Save(Token) error {
+	return fmt.Errorf("token from %s is read-only", s.Variable)
+}
+
+func (s *EnvStore) Delete() error {
+	return fmt.Errorf("token from %s is read-only; unset the variable instead", s.Variable)
+}
+
+func (s *EnvStore) Location() string {
+	return "environment variable " + s.Variable
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countingStore counts how often the wrapped store is loaded.
+type countingStore struct {
+	Store
+	loads int
+}
+
+func (s *countingStore) Load() (*Token, error) {
+	s.loads++
+	return s.Store.Load()
+}
+
+func TestMigrateFile(t *testing.T) {
+	legacy := Token{AccessToken: "legacy", RefreshToken: "refresh"}
+	tests := []struct {
+		name string
+		// noLegacy skips writing the plaintext file.
+		noLegacy bool
+		existing *Token
+		saveErr  error
+		want     bool
+		wantErr  string
+		// wantLegacyKept reports whether the plaintext file must survive.
+		wantLegacyKept bool
+		wantToken      string
+		wantLoads      int
+	}{
+		{name: "no legacy file", noLegacy: true, wantLoads: 0},
+		{name: "migrates", want: true, wantToken: "legacy", wantLoads: 1},
+		{name: "store already has a token", existing: &Token{AccessToken: "current"}, wantLegacyKept: true, wantToken: "current", wantLoads: 1},
+		{name: "save fails", saveErr: errors.New("keyring locked"), wantErr: "migrate token: keyring locked", wantLegacyKept: true, wantLoads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token.json")
+			if !tt.noLegacy {
+				if err := SaveToken(path, legacy); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mem := &memStore{token: tt.existing, saveErr: tt.saveErr}
+			dst := &countingStore{Store: mem}
+
+			got, err := MigrateFile(path, dst)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("MigrateFile = %v, want %v", got, tt.want)
+			}
+			if dst.loads != tt.wantLoads {
+				t.Errorf("loaded the store %d times, want %d", dst.loads, tt.wantLoads)
+			}
+			_, statErr := os.Stat(path)
+			if kept := statErr == nil; kept != tt.wantLegacyKept && !tt.noLegacy {
+				t.Errorf("plaintext file kept = %v, want %v", kept, tt.wantLegacyKept)
+			}
+			if tt.wantToken != "" && (mem.token == nil || mem.token.AccessToken != tt.wantToken) {
+				t.Errorf("store holds %+v, want %s", mem.token, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestMigrateFileToEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.json")
+	if err := SaveToken(path, Token{AccessToken: "legacy", RefreshToken: "refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(StoreOptions{Backend: BackendEncryptedFile, Path: path, Passphrase: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := MigrateFile(path, store)
+	if err != nil || !migrated {
+		t.Fatalf("MigrateFile = %v, %v; want true, nil", migrated, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("plaintext token still present: %v", err)
+	}
+	got, err := (&EncryptedFileStore{Path: path + ".enc", Passphrase: "secret"}).Load()
+	if err != nil || got == nil || got.AccessToken != "legacy" || got.RefreshToken != "refresh" {
+		t.Errorf("encrypted store holds %+v, %v; want the legacy token", got, err)
+	}
+
+	// Nothing is left to migrate on the next run.
+	if migrated, err := MigrateFile(path, store); err != nil || migrated {
+		t.Errorf("second MigrateFile = %v, %v; want false, nil", migrated, err)
+	}
+}
+
+func TestMigrateFileSamePath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := SaveToken(path, Token{AccessToken: "legacy"}); err != nil {
+		t.Fatal(err)
+	}
+	if migrated, err := MigrateFile(path, &FileStore{Path: path}); err != nil || migrated {
+		t.Errorf("MigrateFile = %v, %v; want false, nil", migrated, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("token file removed: %v", err)
+	}
+}
+
+func TestMigrateFileCorruptLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dst := &memStore{}
+	if _, err := MigrateFile(path, dst); err == nil || !strings.Contains(err.Error(), "decode token") {
+		t.Errorf("err = %v, want a decode error", err)
+	}
+	if dst.token != nil {
+		t.Errorf("store holds %+v after a failed migration", dst.token)
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		opts    StoreOptions
+		want    string
+		wantErr string
+	}{
+		{opts: StoreOptions{Path: "/t/token.json"}, want: "/t/token.json"},
+		{opts: StoreOptions{Backend: BackendFile, Path: "/t/token.json"}, want: "/t/token.json"},
+		{opts: StoreOptions{Backend: BackendEncryptedFile, Path: "/t/token.json", Passphrase: "p"}, want: "/t/token.json.enc"},
+		{opts: StoreOptions{Backend: BackendEncryptedFile, Path: "/t/token.json"}, wantErr: "requires a passphrase"},
+		{opts: StoreOptions{Backend: "vault"}, wantErr: `unknown token store "vault"`},
+	}
+	for _, tt := range tests {
+		store, err := NewStore(tt.opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewStore(%+v) err = %v, want %q", tt.opts, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewStore(%+v): %v", tt.opts, err)
+			continue
+		}
+		if got := store.Location(); got != tt.want {
+			t.Errorf("NewStore(%+v).Location() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshLeeway is how long before ExpiresAt a token is treated as expired.
+const refreshLeeway = time.Minute
+
+// ErrNotLoggedIn is returned when no token has been stored yet.
+var ErrNotLoggedIn = errors.New("not logged in; run `codestash login` first")
+
+// RefreshFunc exchanges a refresh token for a new token.
+type RefreshFunc func(ctx context.Context, refreshToken string) (Token, error)
+
+// TokenSource hands out access tokens from a Store, refreshing and
+// persisting them when they are about to expire.
+type TokenSource struct {
+	store   Store
+	refresh RefreshFunc
+
+	mu    sync.Mutex
+	token *Token
+}
+
+func NewTokenSource(store Store, refresh RefreshFunc) *TokenSource {
+	return &TokenSource{
+		store:   store,
+		refresh: refresh,
+	}
+}
+
+// Token returns a valid token, refreshing it first if it expires soon.
+func (s *TokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if !s.token.expiresWithin(refreshLeeway) {
+		return s.token, nil
+	}
+	if strings.TrimSpace(s.token.RefreshToken) == "" {
+		return nil, errors.New("access token expired; run `codestash login` again")
+	}
+	if err := s.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	return s.token, nil
+}
+
+// Refresh forces a refresh, e.g. after the server rejected the current token.
+func (s *TokenSource) Refresh(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(s.token.RefreshToken) == "" {
+		if _, ok := s.store.(*EnvStore); ok {
+			return nil, fmt.Errorf("access token from %s was rejected", s.store.Location())
+		}
+		return nil, errors.New("access token rejected; run `codestash login` again")
+	}
+	if err := s.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	return s.token, nil
+}
+
+// AccessToken and RefreshAccessToken let a TokenSource authenticate an
+// api.Client.
+func (s *TokenSource) AccessToken(ctx context.Context) (string, error) {
+	token, err := s.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (s *TokenSource) RefreshAccessToken(ctx context.Context) (string, error) {
+	token, err := s.Refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (s *TokenSource) load() error {
+	if s.token != nil {
+		return nil
+	}
+	token, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrNotLoggedIn
+	}
+	s.token = token
+	return nil
+}
+
+func (s *TokenSource) refreshLocked(ctx context.Context) error {
+	if s.refresh == nil {
+		return errors.New("token refresh is not configured")
+	}
+	next, err := s.refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("refresh token: %w", err)
+	}
+	if next.RefreshToken == "" {
+		next.RefreshToken = s.token.RefreshToken
+	}
+	if err := s.store.Save(next); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	s.token = &next
+	return nil
+}
+
+func (t *Token) expiresWithin(d time.Duration) bool {
+	if t.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Now().Add(d).After(t.ExpiresAt)
+}
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k-kanke/code-stash-cli/internal/api"
+)
+
+// memStore is an in-memory Store that counts saves.
+type memStore struct {
+	mu      sync.Mutex
+	token   *Token
+	saves   int
+	saveErr error
+}
+
+func (s *memStore) Load() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, nil
+	}
+	t := *s.token
+	return &t, nil
+}
+
+func (s *memStore) Save(token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.saves++
+	s.token = &token
+	return nil
+}
+
+func (s *memStore) Delete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = nil
+	return nil
+}
+
+func (s *memStore) Location() string { return "memory" }
+
+// tokenServer is an OAuth server whose token endpoint hands out "new-N" for
+// the Nth refresh, and whose userinfo endpoint only accepts valid tokens.
+type tokenServer struct {
+	refreshes atomic.Int32
+	// fail makes the token endpoint reject every refresh.
+	fail bool
+	// valid reports whether the userinfo endpoint accepts a token.
+	valid func(token string) bool
+	url   string
+}
+
+func (ts *tokenServer) start(t *testing.T) *api.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth/token":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["grant_type"] != "refresh_token" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if ts.fail {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "refresh token revoked"})
+				return
+			}
+			// Give concurrent callers a chance to pile up behind the refresh.
+			time.Sleep(10 * time.Millisecond)
+			n := ts.refreshes.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{"access_token": fmt.Sprintf("new-%d", n), "expires_in": 3600})
+		case "/oauth/userinfo":
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ts.valid != nil && !ts.valid(token) {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_token"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"sub": "u1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ts.url = srv.URL
+
+	client, err := api.NewClient(srv.URL, "id", "secret", api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// refresher refreshes through client the way the CLI does.
+func refresher(client *api.Client) RefreshFunc {
+	return func(ctx context.Context, refreshToken string) (Token, error) {
+		resp, apiErr, err := client.RefreshToken(ctx, refreshToken)
+		if err != nil {
+			return Token{}, err
+		}
+		if apiErr != nil {
+			return Token{}, apiErr
+		}
+		return Token{AccessToken: resp.AccessToken, ExpiresAt: time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)}, nil
+	}
+}
+
+func TestTokenSourceToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		expiresIn     time.Duration
+		noExpiry      bool
+		wantToken     string
+		wantRefreshes int32
+	}{
+		{name: "valid", expiresIn: time.Hour, wantToken: "old"},
+		{name: "no expiry", noExpiry: true, wantToken: "old"},
+		{name: "near expiry", expiresIn: refreshLeeway / 2, wantToken: "new-1", wantRefreshes: 1},
+		{name: "expired", expiresIn: -time.Hour, wantToken: "new-1", wantRefreshes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &tokenServer{}
+			client := server.start(t)
+			old := Token{AccessToken: "old", RefreshToken: "refresh"}
+			if !tt.noExpiry {
+				old.ExpiresAt = time.Now().Add(tt.expiresIn)
+			}
+			store := &memStore{token: &old}
+
+			got, err := NewTokenSource(store, refresher(client)).AccessToken(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantToken {
+				t.Errorf("AccessToken = %q, want %q", got, tt.wantToken)
+			}
+			if n := server.refreshes.Load(); n != tt.wantRefreshes {
+				t.Errorf("refreshed %d times, want %d", n, tt.wantRefreshes)
+			}
+			if tt.wantRefreshes == 0 {
+				if store.saves != 0 {
+					t.Errorf("saved %d times without a refresh", store.saves)
+				}
+				return
+			}
+			// The refreshed token is persisted, keeping the refresh token
+			// the server did not replace.
+			if store.saves != 1 || store.token.AccessToken != tt.wantToken || store.token.RefreshToken != "refresh" {
+				t.Errorf("store holds %+v after %d saves, want %s with the old refresh token", store.token, store.saves, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestTokenSourceConcurrentRefresh(t *testing.T) {
+	server := &tokenServer{}
+	client := server.start(t)
+	store := &memStore{token: &Token{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now().Add(-time.Minute)}}
+	ts := NewTokenSource(store, refresher(client))
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 8)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], errs[i] = ts.AccessToken(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] != "new-1" {
+			t.Errorf("caller %d got %q, %v; want new-1", i, tokens[i], errs[i])
+		}
+	}
+	if n := server.refreshes.Load(); n != 1 {
+		t.Errorf("refreshed %d times for concurrent callers, want 1", n)
+	}
+	if store.saves != 1 {
+		t.Errorf("saved %d times, want 1", store.saves)
+	}
+}
+
+func TestTokenSourceRefreshOnUnauthorized(t *testing.T) {
+	server := &tokenServer{valid: func(token string) bool { return token == "new-1" }}
+	client := server.start(t)
+	// The token looks valid locally, but the server has revoked it.
+	store := &memStore{token: &Token{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)}}
+	ts := NewTokenSource(store, refresher(client))
+
+	authed, err := api.NewClient(server.url, "id", "secret", api.WithTokenSource(ts), api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authed.UserInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.refreshes.Load(); n != 1 {
+		t.Errorf("refreshed %d times, want 1", n)
+	}
+	if store.token.AccessToken != "new-1" {
+		t.Errorf("store holds %q, want new-1", store.token.AccessToken)
+	}
+}
+
+func TestTokenSourceErrors(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	saveErr := errors.New("disk full")
+	tests := []struct {
+		name    string
+		token   *Token
+		fail    bool
+		saveErr error
+		// refresh calls Refresh, as after a 401, instead of Token.
+		refresh bool
+		env     bool
+		wantErr string
+		wantAPI string
+	}{
+		{name: "not logged in", wantErr: ErrNotLoggedIn.Error()},
+		{
+			name:    "expired without refresh token",
+			token:   &Token{AccessToken: "old", ExpiresAt: expired},
+			wantErr: "access token expired; run `codestash login` again",
+		},
+		{
+			name:    "rejected without refresh token",
+			token:   &Token{AccessToken: "old"},
+			refresh: true,
+			wantErr: "access token rejected; run `codestash login` again",
+		},
+		{
+			name:    "rejected environment token",
+			refresh: true,
+			env:     true,
+			wantErr: "access token from environment variable CODESTASH_TOKEN was rejected",
+		},
+		{
+			name:    "refresh rejected",
+			token:   &Token{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: expired},
+			fail:    true,
+			wantErr: "refresh token: api error: invalid_grant: refresh token revoked",
+			wantAPI: "invalid_grant",
+		},
+		{
+			name:    "save fails",
+			token:   &Token{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: expired},
+			saveErr: saveErr,
+			wantErr: "save token: disk full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &tokenServer{fail: tt.fail}
+			client := server.start(t)
+			var store Store = &memStore{token: tt.token, saveErr: tt.saveErr}
+			if tt.env {
+				store = &EnvStore{Variable: "CODESTASH_TOKEN", Value: "pat"}
+			}
+			ts := NewTokenSource(store, refresher(client))
+
+			var err error
+			if tt.refresh {
+				_, err = ts.Refresh(context.Background())
+			} else {
+				_, err = ts.Token(context.Background())
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantAPI != "" {
+				var apiErr *api.Error
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.wantAPI {
+					t.Errorf("err = %v, want an %s api error in the chain", err, tt.wantAPI)
+				}
+			}
+			// A failed refresh leaves the stored token alone.
+			if ms, ok := store.(*memStore); ok && ms.token != nil && ms.token.AccessToken != "old" {
+				t.Errorf("store holds %q after a failed refresh", ms.token.AccessToken)
+			}
+		})
+	}
+}
@@ -51,3 +51,13 @@ func LoadToken(path string) (*Token, error) {
 	}
 	return &token, nil
 }
+
+func DeleteToken(path string) error {
+	if path == "" {
+		return errors.New("token path is empty")
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove token file: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,22 +15,91 @@ type Config struct {
 	ClientID     string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
 	TokenPath    string `mapstructure:"token_path"`
+	// TokenStore selects the token backend: file, secret-service or encrypted-file.
+	TokenStore      string `mapstructure:"token_store"`
+	TokenPassphrase string `mapstructure:"token_passphrase"`
+	// Retries is how many times idempotent requests are retried after a
+	// transient failure (429, 502-504, connection errors).
+	Retries        int           `mapstructure:"retries"`
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	// Profile is the name of the profile applied on top of the top-level
+	// settings, or empty when none is active.
+	Profile string `mapstructure:"-"`
 }
 
-func Load() (*Config, error) {
+// envPrefix namespaces the environment variables that override config keys,
+// so that generic names such as PROFILE, TOKEN or TIMEOUT are left alone.
+const envPrefix = "CODESTASH"
+
+// ReadEnv makes CODESTASH_<KEY> environment variables, such as
+// CODESTASH_PROFILE or CODESTASH_API_BASE_URL, override config keys.
+func ReadEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.AutomaticEnv()
+}
+
+// Load reads the config and applies the named profile. An empty name falls
+// back to the current profile from the config file, if any.
+func Load(profile string) (*Config, error) {
 	setDefaults()
 
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(profile)
+	if name == "" {
+		name = profiles.Current
+	}
+	if name == "" {
+		return &cfg, nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+	p, ok := profiles.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found; run `codestash profile list`", name)
+	}
+	applyProfile(&cfg, p)
 	return &cfg, nil
 }
 
+func applyProfile(cfg *Config, p Profile) {
+	cfg.Profile = p.Name
+	if p.APIBaseURL != "" {
+		cfg.APIBaseURL = p.APIBaseURL
+	}
+	if p.ClientID != "" {
+		cfg.ClientID = p.ClientID
+	}
+	if p.ClientSecret != "" {
+		cfg.ClientSecret = p.ClientSecret
+	}
+	if p.TokenPath != "" {
+		cfg.TokenPath = p.TokenPath
+	} else {
+		cfg.TokenPath = defaultProfileTokenPath(p.Name)
+	}
+}
+
 func setDefaults() {
 	viper.SetDefault("api_base_url", "http://localhost:8085")
 	viper.SetDefault("client_id", "7d8b1e7d-8c8d-4c7e-9f4a-2f0afc1a0f01")
 	viper.SetDefault("client_secret", "cli-device-secret")
+	viper.SetDefault("token_store", "file")
+	viper.SetDefault("retries", 2)
+	viper.SetDefault("retry_base_delay", "500ms")
+	viper.SetDefault("retry_max_delay", "10s")
+	viper.SetDefault("timeout", "15s")
+	_ = viper.BindEnv("token_passphrase", "CODESTASH_TOKEN_PASSPHRASE")
 
 	tokenPath := defaultTokenPath()
 	if tokenPath != "" {
@@ -44,3 +115,11 @@ func defaultTokenPath() string {
 
 	return filepath.Join(home, ".config", "codestash", "token.json")
 }
+
+func configDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", ".codestash")
+	}
+	return filepath.Join(home, ".config", "codestash")
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// useConfigFile points viper at a config file in a temporary home and
+// returns its path.
+func useConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join(home, name)
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	viper.SetConfigFile(path)
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	const config = `api_base_url: https://notes.example.com
+client_id: top-client
+current_profile: home
+profiles:
+  home:
+    client_id: home-client
+  work:
+    api_base_url: https://work.example.com
+    token_path: /tokens/work.json
+`
+	tests := []struct {
+		profile     string
+		wantProfile string
+		wantURL     string
+		wantClient  string
+		wantToken   string
+		wantErr     string
+	}{
+		{
+			profile: "", wantProfile: "home", wantURL: "https://notes.example.com", wantClient: "home-client",
+			wantToken: filepath.Join("tokens", "home.json"),
+		},
+		{
+			profile: "work", wantProfile: "work", wantURL: "https://work.example.com", wantClient: "top-client",
+			wantToken: "/tokens/work.json",
+		},
+		{profile: "missing", wantErr: `profile "missing" not found`},
+		{profile: "/etc/profile", wantErr: "invalid profile name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			useConfigFile(t, ".code-stash-cli.yaml", config)
+			if err := viper.ReadInConfig(); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Profile != tt.wantProfile || cfg.APIBaseURL != tt.wantURL || cfg.ClientID != tt.wantClient {
+				t.Errorf("Load = profile %q, host %q, client %q; want %q, %q, %q",
+					cfg.Profile, cfg.APIBaseURL, cfg.ClientID, tt.wantProfile, tt.wantURL, tt.wantClient)
+			}
+			if !strings.HasSuffix(cfg.TokenPath, tt.wantToken) {
+				t.Errorf("token path = %q, want one ending in %q", cfg.TokenPath, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestReadEnv(t *testing.T) {
+	useConfigFile(t, ".code-stash-cli.yaml", "")
+	ReadEnv()
+	// Generic names that other tools set must not leak into the config.
+	t.Setenv("PROFILE", "/etc/profile")
+	t.Setenv("API_BASE_URL", "https://other.example.com")
+	t.Setenv("CODESTASH_CLIENT_ID", "env-client")
+
+	if got := viper.GetString("profile"); got != "" {
+		t.Errorf("profile = %q, want PROFILE to be ignored", got)
+	}
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIBaseURL != "http://localhost:8085" {
+		t.Errorf("api_base_url = %q, want API_BASE_URL to be ignored", cfg.APIBaseURL)
+	}
+	if cfg.ClientID != "env-client" {
+		t.Errorf("client_id = %q, want CODESTASH_CLIENT_ID", cfg.ClientID)
+	}
+
+	t.Setenv("CODESTASH_PROFILE", "work")
+	if got := viper.GetString("profile"); got != "work" {
+		t.Errorf("profile = %q, want CODESTASH_PROFILE", got)
+	}
+}
+
+func TestReadEnvRetrySettings(t *testing.T) {
+	useConfigFile(t, ".code-stash-cli.yaml", "")
+	ReadEnv()
+	// Unprefixed names are common in CI and would fail to parse here.
+	t.Setenv("RETRIES", "many")
+	t.Setenv("TIMEOUT", "forever")
+	t.Setenv("RETRY_MAX_DELAY", "1h")
+	t.Setenv("DEBUG", "1")
+	t.Setenv("CODESTASH_RETRIES", "5")
+	t.Setenv("CODESTASH_TIMEOUT", "30s")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Retries != 5 || cfg.Timeout != 30*time.Second {
+		t.Errorf("retries %d, timeout %v; want 5 and 30s from CODESTASH_ variables", cfg.Retries, cfg.Timeout)
+	}
+	if cfg.RetryBaseDelay != 500*time.Millisecond || cfg.RetryMaxDelay != 10*time.Second {
+		t.Errorf("retry delays %v, %v; want the defaults", cfg.RetryBaseDelay, cfg.RetryMaxDelay)
+	}
+	if viper.GetBool("debug") {
+		t.Error("debug is on, want DEBUG to be ignored")
+	}
+}
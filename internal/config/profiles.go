@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// Profile overrides the API host, client credentials and token location for
+// one account. Empty fields fall back to the top-level config.
+type Profile struct {
+	Name         string `json:"-" yaml:"-"`
+	APIBaseURL   string `json:"api_base_url,omitempty" yaml:"api_base_url,omitempty"`
+	ClientID     string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	TokenPath    string `json:"token_path,omitempty" yaml:"token_path,omitempty"`
+}
+
+// Profiles are kept in the config file, keyed by name under "profiles",
+// with the default profile's name under "current_profile".
+type Profiles struct {
+	Current  string
+	Profiles map[string]Profile
+	path     string
+}
+
+// profileName is what a profile may be called. Names become file names
+// under the config directory, so they cannot contain path separators or
+// start with a dot.
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateProfileName reports whether name can be used for a profile.
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return errors.New("profile name is required")
+	}
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-', not starting with '.'", name)
+	}
+	return nil
+}
+
+// File returns the config file in use, or $HOME/.code-stash-cli.yaml when
+// there is none yet.
+func File() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".code-stash-cli.yaml"
+	}
+	return filepath.Join(home, ".code-stash-cli.yaml")
+}
+
+func LoadProfiles() (*Profiles, error) {
+	path := File()
+	p := &Profiles{
+		Profiles: make(map[string]Profile),
+		path:     path,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := checkConfigFormat(path); err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so this reads both.
+	var file struct {
+		Current  string             `yaml:"current_profile"`
+		Profiles map[string]Profile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode profiles in %s: %w", path, err)
+	}
+	p.Current = file.Current
+	for name, profile := range file.Profiles {
+		profile.Name = name
+		p.Profiles[name] = profile
+	}
+	return p, nil
+}
+
+// Save writes the profiles back to the config file, leaving its other
+// settings as they are.
+func (p *Profiles) Save() error {
+	if err := checkConfigFormat(p.path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	var profiles map[string]Profile
+	if len(p.Profiles) > 0 {
+		profiles = p.Profiles
+	}
+	if strings.EqualFold(filepath.Ext(p.path), ".json") {
+		data, err = setJSONKeys(data, p.Current, profiles)
+	} else {
+		data, err = setYAMLKeys(data, p.Current, profiles)
+	}
+	if err != nil {
+		return fmt.Errorf("encode profiles: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(p.path, data, 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+func checkConfigFormat(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case "", ".yaml", ".yml", ".json":
+		return nil
+	}
+	return fmt.Errorf("profiles can only be stored in a YAML or JSON config file, not %s", path)
+}
+
+// setYAMLKeys sets current_profile and profiles in a YAML document, keeping
+// the other keys and their comments. Empty values remove the key.
+func setYAMLKeys(data []byte, current string, profiles map[string]Profile) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("config file is not a mapping")
+	}
+	if err := setYAMLKey(root, "current_profile", current, current == ""); err != nil {
+		return nil, err
+	}
+	if err := setYAMLKey(root, "profiles", profiles, profiles == nil); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func setYAMLKey(mapping *yaml.Node, key string, value any, remove bool) error {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if remove {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return nil
+		}
+		return mapping.Content[i+1].Encode(value)
+	}
+	if remove {
+		return nil
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+	return nil
+}
+
+// setJSONKeys is setYAMLKeys for a JSON config file.
+func setJSONKeys(data []byte, current string, profiles map[string]Profile) ([]byte, error) {
+	doc := make(map[string]any)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	delete(doc, "current_profile")
+	delete(doc, "profiles")
+	if current != "" {
+		doc["current_profile"] = current
+	}
+	if profiles != nil {
+		doc["profiles"] = profiles
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func (p *Profiles) Add(profile Profile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if err := ValidateProfileName(profile.Name); err != nil {
+		return err
+	}
+	p.Profiles[profile.Name] = profile
+	return nil
+}
+
+func (p *Profiles) Remove(name string) error {
+	if _, ok := p.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	delete(p.Profiles, name)
+	if p.Current == name {
+		p.Current = ""
+	}
+	return nil
+}
+
+func (p *Profiles) Use(name string) error {
+	if _, ok := p.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	p.Current = name
+	return nil
+}
+
+func defaultProfileTokenPath(name string) string {
+	return filepath.Join(configDir(), "tokens", name+".json")
+}
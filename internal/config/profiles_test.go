@@ -0,0 +1,214 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestValidateProfileName(t *testing.T) {
+	tests := map[string]bool{
+		"work":         true,
+		"dev-1":        true,
+		"a.b_c":        true,
+		"Prod2":        true,
+		"":             false,
+		".hidden":      false,
+		"-flag":        false,
+		"../x":         false,
+		"a/b":          false,
+		`a\b`:          false,
+		"two words":    false,
+		"/etc/profile": false,
+	}
+	for name, valid := range tests {
+		if err := ValidateProfileName(name); (err == nil) != valid {
+			t.Errorf("ValidateProfileName(%q) = %v, want valid = %v", name, err, valid)
+		}
+	}
+}
+
+func TestProfilesSaveYAML(t *testing.T) {
+	const original = `# CodeStash CLI settings
+api_base_url: https://notes.example.com # shared host
+retries: 3
+`
+	tests := []struct {
+		name   string
+		config string
+		edit   func(p *Profiles) error
+		// want and notWant are substrings the saved file must or must not
+		// contain.
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "add keeps other settings and comments",
+			config: original,
+			edit: func(p *Profiles) error {
+				if err := p.Add(Profile{Name: "work", APIBaseURL: "https://work.example.com"}); err != nil {
+					return err
+				}
+				return p.Use("work")
+			},
+			want: []string{
+				"# CodeStash CLI settings\n",
+				"api_base_url: https://notes.example.com # shared host\n",
+				"retries: 3\n",
+				"current_profile: work\n",
+				"profiles:\n  work:\n    api_base_url: https://work.example.com\n",
+			},
+		},
+		{
+			name: "overwrite",
+			config: original + `current_profile: work
+profiles:
+  # the day job
+  work:
+    api_base_url: https://old.example.com
+  home:
+    client_id: home-client
+`,
+			edit: func(p *Profiles) error {
+				return p.Add(Profile{Name: "work", APIBaseURL: "https://new.example.com"})
+			},
+			want:    []string{"# CodeStash CLI settings\n", "current_profile: work\n", "https://new.example.com", "client_id: home-client"},
+			notWant: []string{"https://old.example.com"},
+		},
+		{
+			name: "remove the current profile",
+			config: original + `current_profile: work
+profiles:
+  work:
+    api_base_url: https://work.example.com
+  home:
+    client_id: home-client
+`,
+			edit:    func(p *Profiles) error { return p.Remove("work") },
+			want:    []string{"retries: 3\n", "profiles:\n  home:\n    client_id: home-client\n"},
+			notWant: []string{"current_profile", "work"},
+		},
+		{
+			name: "remove the last profile",
+			config: original + `profiles:
+  work:
+    api_base_url: https://work.example.com
+`,
+			edit:    func(p *Profiles) error { return p.Remove("work") },
+			want:    []string{"# CodeStash CLI settings\n", "retries: 3\n"},
+			notWant: []string{"profiles", "work"},
+		},
+		{
+			name: "no config file yet",
+			edit: func(p *Profiles) error { return p.Add(Profile{Name: "work", TokenPath: "/tokens/work.json"}) },
+			want: []string{"profiles:\n  work:\n    token_path: /tokens/work.json\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useConfigFile(t, ".code-stash-cli.yaml", tt.config)
+			p, err := LoadProfiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.edit(p); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := readFile(t, path)
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("saved config does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("saved config contains %q:\n%s", s, got)
+				}
+			}
+
+			// The file reads back as what was saved.
+			again, err := LoadProfiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Current != p.Current || len(again.Profiles) != len(p.Profiles) {
+				t.Errorf("reloaded %q with %d profiles, want %q with %d", again.Current, len(again.Profiles), p.Current, len(p.Profiles))
+			}
+			for name, want := range p.Profiles {
+				if got := again.Profiles[name]; got != want {
+					t.Errorf("reloaded profile %q = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProfilesSaveJSON(t *testing.T) {
+	path := useConfigFile(t, "config.json", `{"retries": 3, "api_base_url": "https://notes.example.com"}`)
+	p, err := LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Add(Profile{Name: "work", ClientID: "work-client"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Use("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "api_base_url": "https://notes.example.com",
+  "current_profile": "work",
+  "profiles": {
+    "work": {
+      "client_id": "work-client"
+    }
+  },
+  "retries": 3
+}
+`
+	if got := readFile(t, path); got != want {
+		t.Errorf("saved config =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProfilesErrors(t *testing.T) {
+	useConfigFile(t, ".code-stash-cli.yaml", "profiles:\n  work: {}\n")
+	p, err := LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Add(Profile{Name: "../escape"}); err == nil {
+		t.Error("Add accepted an invalid name")
+	}
+	if _, ok := p.Profiles["../escape"]; ok {
+		t.Error("Add stored an invalid name")
+	}
+	if err := p.Use("missing"); err == nil {
+		t.Error("Use accepted an unknown profile")
+	}
+	if err := p.Remove("missing"); err == nil {
+		t.Error("Remove accepted an unknown profile")
+	}
+
+	useConfigFile(t, "config.toml", "retries = 3\n")
+	if _, err := LoadProfiles(); err == nil || !strings.Contains(err.Error(), "YAML or JSON") {
+		t.Errorf("LoadProfiles from TOML = %v, want a format error", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Package diff compares texts line by line. It computes minimal edit
+// scripts with Myers' algorithm and builds a three-way merge on top of them.
+package diff
+
+import "strings"
+
+// Op is one step of an edit script.
+type Op byte
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Lines splits s into lines, keeping each line's trailing newline so that a
+// missing newline at the end of the text survives a round trip.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Edits returns a shortest edit script turning a into b. Equal and Delete
+// consume a line of a, Equal and Insert consume a line of b.
+func Edits(a, b []string) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for range prefix {
+		ops = append(ops, Equal)
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for range suffix {
+		ops = append(ops, Equal)
+	}
+	return ops
+}
+
+// myers implements the O((N+M)D) greedy algorithm from "An O(ND) Difference
+// Algorithm and Its Variations". Only the diagonals reachable at each step
+// are kept, so memory stays O(D²) rather than O((N+M)D).
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		ops := make([]Op, 0, n+m)
+		for range n {
+			ops = append(ops, Delete)
+		}
+		for range m {
+			ops = append(ops, Insert)
+		}
+		return ops
+	}
+
+	// trace[d][k+d] is the furthest x reached on diagonal k after d edits.
+	var trace [][]int
+	prev := []int{0}
+	for d := 0; ; d++ {
+		v := make([]int, 2*d+1)
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && at(prev, d-1, k-1) < at(prev, d-1, k+1)):
+				x = at(prev, d-1, k+1)
+			default:
+				x = at(prev, d-1, k-1) + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		trace = append(trace, v)
+		if done {
+			break
+		}
+		prev = v
+	}
+
+	// Walk back from (n, m), emitting the script in reverse.
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(trace[d-1], d-1, k-1) < at(trace[d-1], d-1, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(trace[d-1], d-1, prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, Equal)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, Insert)
+			y--
+		} else {
+			ops = append(ops, Delete)
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, Equal)
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// at reads diagonal k from a row computed after d edits.
+func at(v []int, d, k int) int {
+	return v[k+d]
+}
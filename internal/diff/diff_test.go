@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\n\n", []string{"a\n", "\n"}},
+	}
+	for _, tt := range tests {
+		got := Lines(tt.in)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Lines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if joined := strings.Join(got, ""); joined != tt.in {
+			t.Errorf("Lines(%q) joins back to %q", tt.in, joined)
+		}
+	}
+}
+
+func TestEdits(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		// edits is the length of a shortest edit script.
+		edits int
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n", 0},
+		{"both empty", "", "", 0},
+		{"insert into empty", "", "a\nb\n", 2},
+		{"delete everything", "a\nb\n", "", 2},
+		{"insert in middle", "a\nc\n", "a\nb\nc\n", 1},
+		{"insert at end", "a\n", "a\nb\n", 1},
+		{"delete in middle", "a\nb\nc\n", "a\nc\n", 1},
+		{"delete at start", "a\nb\n", "b\n", 1},
+		{"replace", "a\nb\nc\n", "a\nB\nc\n", 2},
+		{"missing final newline", "a\nb", "a\nb\n", 2},
+		{"disjoint", "a\nb\n", "c\nd\n", 4},
+		{"paper example", "a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Lines(tt.a), Lines(tt.b)
+			ops := Edits(a, b)
+			if got := applyOps(t, ops, a, b); !slices.Equal(got, b) {
+				t.Fatalf("script %v turns %q into %q, want %q", ops, tt.a, strings.Join(got, ""), tt.b)
+			}
+			edits := 0
+			for _, op := range ops {
+				if op != Equal {
+					edits++
+				}
+			}
+			if edits != tt.edits {
+				t.Errorf("script %v has %d edits, want %d", ops, edits, tt.edits)
+			}
+		})
+	}
+}
+
+func TestEditsOrder(t *testing.T) {
+	got := Edits(Lines("a\nb\nc\n"), Lines("a\nx\nc\nd\n"))
+	want := []Op{Equal, Delete, Insert, Equal, Insert}
+	if !slices.Equal(got, want) {
+		t.Errorf("Edits = %v, want %v", got, want)
+	}
+}
+
+// applyOps runs an edit script over a, taking inserted lines from b, and
+// checks that it consumes both exactly.
+func applyOps(t *testing.T, ops []Op, a, b []string) []string {
+	t.Helper()
+	var out []string
+	i, j := 0, 0
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			if i >= len(a) || j >= len(b) || a[i] != b[j] {
+				t.Fatalf("Equal at a[%d], b[%d] does not match", i, j)
+			}
+			out = append(out, a[i])
+			i++
+			j++
+		case Delete:
+			if i >= len(a) {
+				t.Fatalf("Delete past the end of a")
+			}
+			i++
+		case Insert:
+			if j >= len(b) {
+				t.Fatalf("Insert past the end of b")
+			}
+			out = append(out, b[j])
+			j++
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("script consumed %d/%d lines of a and %d/%d of b", i, len(a), j, len(b))
+	}
+	return out
+}
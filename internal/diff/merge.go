@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written around regions both sides changed differently.
+const (
+	MarkerLocal  = "<<<<<<< local\n"
+	MarkerSep    = "=======\n"
+	MarkerRemote = ">>>>>>> remote\n"
+)
+
+// hunk replaces base[start:end] with lines.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// hunks groups the changes between base and other by the base range they
+// replace.
+func hunks(base, other []string) []hunk {
+	var out []hunk
+	i, j := 0, 0
+	var cur *hunk
+	for _, op := range Edits(base, other) {
+		if op == Equal {
+			if cur != nil {
+				out = append(out, *cur)
+				cur = nil
+			}
+			i++
+			j++
+			continue
+		}
+		if cur == nil {
+			cur = &hunk{start: i, end: i}
+		}
+		if op == Delete {
+			i++
+			cur.end = i
+		} else {
+			cur.lines = append(cur.lines, other[j])
+			j++
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
+
+// MergeResult is the outcome of a three-way merge.
+type MergeResult struct {
+	Text string
+	// Conflicts counts regions written with conflict markers.
+	Conflicts int
+}
+
+// Merge combines the changes local and remote each made to base. Regions
+// changed by only one side take that side; regions both sides changed the
+// same way are taken once; anything else is written between conflict
+// markers, local first.
+func Merge(base, local, remote string) MergeResult {
+	b := Lines(base)
+	ours := hunks(b, Lines(local))
+	theirs := hunks(b, Lines(remote))
+
+	var out strings.Builder
+	var res MergeResult
+	pos := 0
+	for len(ours) > 0 || len(theirs) > 0 {
+		start := nextStart(ours, theirs)
+		end := start
+
+		// Grow the region until no hunk from either side touches it.
+		var o, t []hunk
+		for {
+			n := 0
+			for len(ours) > 0 && ours[0].start <= end {
+				end = max(end, ours[0].end)
+				o, ours = append(o, ours[0]), ours[1:]
+				n++
+			}
+			for len(theirs) > 0 && theirs[0].start <= end {
+				end = max(end, theirs[0].end)
+				t, theirs = append(t, theirs[0]), theirs[1:]
+				n++
+			}
+			if n == 0 {
+				break
+			}
+		}
+
+		writeLines(&out, b[pos:start])
+		localText := apply(b, o, start, end)
+		remoteText := apply(b, t, start, end)
+		switch {
+		case len(t) == 0:
+			writeLines(&out, localText)
+		case len(o) == 0, slices.Equal(localText, remoteText):
+			writeLines(&out, remoteText)
+		default:
+			res.Conflicts++
+			out.WriteString(MarkerLocal)
+			writeBlock(&out, localText)
+			out.WriteString(MarkerSep)
+			writeBlock(&out, remoteText)
+			out.WriteString(MarkerRemote)
+		}
+		pos = end
+	}
+	writeLines(&out, b[pos:])
+	res.Text = out.String()
+	return res
+}
+
+func nextStart(ours, theirs []hunk) int {
+	switch {
+	case len(ours) == 0:
+		return theirs[0].start
+	case len(theirs) == 0:
+		return ours[0].start
+	}
+	return min(ours[0].start, theirs[0].start)
+}
+
+// apply returns base[start:end] with the given hunks applied.
+func apply(base []string, hs []hunk, start, end int) []string {
+	var out []string
+	pos := start
+	for _, h := range hs {
+		out = append(out, base[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+	}
+}
+
+// writeBlock writes lines inside conflict markers, which must start on
+// their own line even when the text lacks a final newline.
+func writeBlock(b *strings.Builder, lines []string) {
+	writeLines(b, lines)
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		b.WriteByte('\n')
+	}
+}
+
+// HasConflictMarkers reports whether text still contains an unresolved
+// conflict written by Merge.
+func HasConflictMarkers(text string) bool {
+	local, remote := false, false
+	for _, line := range Lines(text) {
+		switch line {
+		case MarkerLocal:
+			local = true
+		case MarkerRemote:
+			remote = local
+		}
+	}
+	return remote
+}
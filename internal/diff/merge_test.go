@@ -0,0 +1,142 @@
+package diff
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, local, remote string
+		want                string
+		conflicts           int
+	}{
+		{
+			name: "identical",
+			base: "a\nb\n", local: "a\nb\n", remote: "a\nb\n",
+			want: "a\nb\n",
+		},
+		{
+			name: "only local changed",
+			base: "a\nb\nc\n", local: "a\nB\nc\n", remote: "a\nb\nc\n",
+			want: "a\nB\nc\n",
+		},
+		{
+			name: "only remote changed",
+			base: "a\nb\nc\n", local: "a\nb\nc\n", remote: "a\nb\nC\n",
+			want: "a\nb\nC\n",
+		},
+		{
+			name: "insert on one side",
+			base: "a\nc\n", local: "a\nb\nc\n", remote: "a\nc\n",
+			want: "a\nb\nc\n",
+		},
+		{
+			name: "delete on one side",
+			base: "a\nb\nc\n", local: "a\nb\nc\n", remote: "a\nc\n",
+			want: "a\nc\n",
+		},
+		{
+			name: "non-overlapping edits",
+			base: "a\nb\nc\nd\ne\n", local: "A\nb\nc\nd\ne\n", remote: "a\nb\nc\nd\nE\n",
+			want: "A\nb\nc\nd\nE\n",
+		},
+		{
+			name: "insert and delete apart",
+			base: "a\nb\nc\nd\n", local: "a\nx\nb\nc\nd\n", remote: "a\nb\nc\n",
+			want: "a\nx\nb\nc\n",
+		},
+		{
+			name: "same edit on both sides",
+			base: "a\nb\nc\n", local: "a\nB\nc\n", remote: "a\nB\nc\n",
+			want: "a\nB\nc\n",
+		},
+		{
+			name: "same delete on both sides",
+			base: "a\nb\nc\n", local: "a\nc\n", remote: "a\nc\n",
+			want: "a\nc\n",
+		},
+		{
+			name: "conflicting edits",
+			base: "a\nb\nc\n", local: "a\nL\nc\n", remote: "a\nR\nc\n",
+			want:      "a\n<<<<<<< local\nL\n=======\nR\n>>>>>>> remote\nc\n",
+			conflicts: 1,
+		},
+		{
+			name: "local delete, remote edit",
+			base: "a\nb\nc\n", local: "a\nc\n", remote: "a\nR\nc\n",
+			want:      "a\n<<<<<<< local\n=======\nR\n>>>>>>> remote\nc\n",
+			conflicts: 1,
+		},
+		{
+			name: "local edit, remote delete",
+			base: "a\nb\nc\n", local: "a\nL\nc\n", remote: "a\nc\n",
+			want:      "a\n<<<<<<< local\nL\n=======\n>>>>>>> remote\nc\n",
+			conflicts: 1,
+		},
+		{
+			name: "different inserts at the same place",
+			base: "a\nc\n", local: "a\nL\nc\n", remote: "a\nR\nc\n",
+			want:      "a\n<<<<<<< local\nL\n=======\nR\n>>>>>>> remote\nc\n",
+			conflicts: 1,
+		},
+		{
+			name: "two conflicts",
+			base: "a\nb\nc\nd\ne\n", local: "L1\nb\nc\nd\nL2\n", remote: "R1\nb\nc\nd\nR2\n",
+			want:      "<<<<<<< local\nL1\n=======\nR1\n>>>>>>> remote\nb\nc\nd\n<<<<<<< local\nL2\n=======\nR2\n>>>>>>> remote\n",
+			conflicts: 2,
+		},
+		{
+			name: "missing final newline kept",
+			base: "a\nb", local: "A\nb", remote: "a\nb",
+			want: "A\nb",
+		},
+		{
+			name: "final newline added on one side",
+			base: "a", local: "a\n", remote: "a",
+			want: "a\n",
+		},
+		{
+			name: "conflict without final newline",
+			base: "a", local: "L", remote: "R",
+			want:      "<<<<<<< local\nL\n=======\nR\n>>>>>>> remote\n",
+			conflicts: 1,
+		},
+		{
+			name: "empty base",
+			base: "", local: "a\n", remote: "a\n",
+			want: "a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Merge(tt.base, tt.local, tt.remote)
+			if got.Text != tt.want {
+				t.Errorf("Merge text =\n%q\nwant\n%q", got.Text, tt.want)
+			}
+			if got.Conflicts != tt.conflicts {
+				t.Errorf("Merge conflicts = %d, want %d", got.Conflicts, tt.conflicts)
+			}
+			if HasConflictMarkers(got.Text) != (tt.conflicts > 0) {
+				t.Errorf("HasConflictMarkers = %v, want %v", !(tt.conflicts > 0), tt.conflicts > 0)
+			}
+		})
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"no markers", "a\nb\n", false},
+		{"full conflict", "<<<<<<< local\na\n=======\nb\n>>>>>>> remote\n", true},
+		{"resolved local side only", "<<<<<<< local\na\n", false},
+		{"remote before local", ">>>>>>> remote\n<<<<<<< local\n", false},
+		{"markers inside a line", "x <<<<<<< local\n>>>>>>> remote y\n", false},
+	}
+	for _, tt := range tests {
+		if got := HasConflictMarkers(tt.text); got != tt.want {
+			t.Errorf("%s: HasConflictMarkers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified returns a unified diff turning a into b with context lines around
+// each change, or "" if the texts are equal. nameA and nameB label the
+// "---" and "+++" header lines.
+func Unified(a, b, nameA, nameB string, context int) string {
+	la, lb := Lines(a), Lines(b)
+	ops := Edits(la, lb)
+
+	// Positions in ops where a line was deleted or inserted.
+	var changes []int
+	for i, op := range ops {
+		if op != Equal {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+
+	// ai and bi count the lines of a and b consumed before ops[i].
+	ai, bi, i := 0, 0, 0
+	advance := func(to int) {
+		for ; i < to; i++ {
+			if ops[i] != Insert {
+				ai++
+			}
+			if ops[i] != Delete {
+				bi++
+			}
+		}
+	}
+
+	for c := 0; c < len(changes); {
+		// Changes closer than twice the context share a hunk.
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context+1 {
+			last++
+		}
+		start := max(changes[c]-context, 0)
+		end := min(changes[last]+context+1, len(ops))
+		c = last + 1
+
+		advance(start)
+		aStart, bStart := ai, bi
+		var body strings.Builder
+		for ; i < end; i++ {
+			switch ops[i] {
+			case Equal:
+				writeDiffLine(&body, ' ', la[ai])
+				ai++
+				bi++
+			case Delete:
+				writeDiffLine(&body, '-', la[ai])
+				ai++
+			case Insert:
+				writeDiffLine(&body, '+', lb[bi])
+				bi++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, ai-aStart), hunkRange(bStart, bi-bStart))
+		out.WriteString(body.String())
+	}
+	return out.String()
+}
+
+// hunkRange formats a hunk's line range the way diff -u does: 1-based,
+// with the count omitted when it is 1 and an empty range naming the line
+// before it.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func writeDiffLine(b *strings.Builder, prefix byte, line string) {
+	b.WriteByte(prefix)
+	b.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
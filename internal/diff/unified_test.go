@@ -0,0 +1,58 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "equal", a: "a\nb\n", b: "a\nb\n", context: 3,
+			want: "",
+		},
+		{
+			name: "one change", a: "a\nb\nc\n", b: "a\nB\nc\n", context: 3,
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "insert into empty", a: "", b: "x\n", context: 3,
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "delete everything", a: "x\n", b: "", context: 3,
+			want: "--- a\n+++ b\n@@ -1 +0,0 @@\n-x\n",
+		},
+		{
+			name: "missing final newline", a: "a", b: "a\n", context: 3,
+			want: "--- a\n+++ b\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n",
+		},
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:       "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+y\n",
+		},
+		{
+			name:    "nearby changes share a hunk",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "1\nB\n3\nD\n5\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,5 +1,5 @@\n 1\n-2\n+B\n 3\n-4\n+D\n 5\n",
+		},
+		{
+			name: "no context", a: "a\nb\nc\n", b: "a\nB\nc\n", context: 0,
+			want: "--- a\n+++ b\n@@ -2 +2 @@\n-b\n+B\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.a, tt.b, "a", "b", tt.context); got != tt.want {
+				t.Errorf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package highlight adds ANSI colors to source code for terminal output.
+// It is a small lexer that knows comments, strings, numbers and keywords
+// for common languages; it does not try to be a full parser.
+package highlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/k-kanke/code-stash-cli/internal/lang"
+)
+
+const (
+	reset   = "\x1b[0m"
+	keyword = "\x1b[35m"
+	str     = "\x1b[32m"
+	comment = "\x1b[90m"
+	number  = "\x1b[36m"
+)
+
+type syntax struct {
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	keywords     map[string]bool
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var cFamily = [2]string{"/*", "*/"}
+
+var languages = map[string]syntax{
+	"go": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'`",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false iota`),
+	},
+	"python": {
+		lineComments: []string{"#"}, quotes: "\"'",
+		keywords: words(`and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield None True False`),
+	},
+	"javascript": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'`",
+		keywords: words(`async await break case catch class const continue debugger default delete do else export
+			extends finally for function if import in instanceof let new of return super switch this throw try
+			typeof var void while yield null undefined true false`),
+	},
+	"typescript": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'`",
+		keywords: words(`abstract any as async await boolean break case catch class const continue declare default
+			delete do else enum export extends finally for from function if implements import in instanceof
+			interface keyof let namespace new number of private protected public readonly return string super
+			switch this throw try type typeof var void while null undefined true false`),
+	},
+	"rust": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"",
+		keywords: words(`as async await break const continue crate dyn else enum extern fn for if impl in let loop
+			match mod move mut pub ref return self Self static struct super trait type unsafe use where while
+			true false`),
+	},
+	"java": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'",
+		keywords: words(`abstract boolean break byte case catch char class const continue default do double else
+			enum extends final finally float for if implements import instanceof int interface long new package
+			private protected public return short static super switch this throw throws try void while null
+			true false var`),
+	},
+	"c": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'",
+		keywords: words(`auto break case char const continue default do double else enum extern float for goto if
+			int long register return short signed sizeof static struct switch typedef union unsigned void
+			volatile while NULL`),
+	},
+	"cpp": {
+		lineComments: []string{"//"}, blockComment: cFamily, quotes: "\"'",
+		keywords: words(`auto bool break case catch char class const constexpr continue default delete do double
+			else enum explicit extern false float for friend goto if inline int long namespace new nullptr
+			operator private protected public return short signed sizeof static struct switch template this
+			throw true try typedef typename union unsigned using virtual void volatile while`),
+	},
+	"ruby": {
+		lineComments: []string{"#"}, quotes: "\"'",
+		keywords: words(`alias and begin break case class def defined? do else elsif end ensure false for if in
+			module next nil not or redo rescue retry return self super then true undef unless until when while yield`),
+	},
+	"shell": {
+		lineComments: []string{"#"}, quotes: "\"'",
+		keywords: words(`if then else elif fi case esac for select while until do done in function return
+			local export readonly set unset echo exit`),
+	},
+	"sql": {
+		lineComments: []string{"--"}, blockComment: cFamily, quotes: "'\"",
+		keywords: words(`select from where insert into values update set delete create table alter drop index
+			join left right inner outer on group by order having limit offset and or not null as distinct
+			union all primary key foreign references default SELECT FROM WHERE INSERT INTO VALUES UPDATE SET
+			DELETE CREATE TABLE ALTER DROP INDEX JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT
+			OFFSET AND OR NOT NULL AS DISTINCT UNION ALL PRIMARY KEY FOREIGN REFERENCES DEFAULT`),
+	},
+	"yaml": {
+		lineComments: []string{"#"}, quotes: "\"'",
+		keywords: words(`true false null yes no`),
+	},
+	"json": {
+		quotes:   "\"",
+		keywords: words(`true false null`),
+	},
+}
+
+// Supported reports whether language has highlighting rules.
+func Supported(language string) bool {
+	_, ok := lookup(language)
+	return ok
+}
+
+func lookup(language string) (syntax, bool) {
+	s, ok := languages[lang.Canonical(language)]
+	return s, ok
+}
+
+// Code returns src with ANSI color codes for language. Unknown languages are
+// returned unchanged.
+func Code(src, language string) string {
+	syn, ok := lookup(language)
+	if !ok {
+		return src
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		rest := src[i:]
+
+		if open := syn.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			n := len(rest)
+			if end := strings.Index(rest[len(open):], syn.blockComment[1]); end >= 0 {
+				n = len(open) + end + len(syn.blockComment[1])
+			}
+			i += paint(&b, rest[:n], comment)
+			continue
+		}
+		if lineCommentAt(rest, syn.lineComments) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			i += paint(&b, rest[:n], comment)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case strings.ContainsRune(syn.quotes, r):
+			i += paint(&b, rest[:quotedLen(rest, r)], str)
+		case unicode.IsDigit(r):
+			n := scan(rest, func(r rune) bool { return isIdent(r) || unicode.IsDigit(r) || r == '.' })
+			i += paint(&b, rest[:n], number)
+		case isIdent(r):
+			n := scan(rest, func(r rune) bool { return isIdent(r) || unicode.IsDigit(r) })
+			if word := rest[:n]; syn.keywords[word] {
+				paint(&b, word, keyword)
+			} else {
+				b.WriteString(word)
+			}
+			i += n
+		default:
+			b.WriteString(rest[:size])
+			i += size
+		}
+	}
+	return b.String()
+}
+
+// quotedLen returns the byte length of the string literal at the start of s.
+// Unterminated literals end at the line break, except for backquotes.
+func quotedLen(s string, quote rune) int {
+	i := utf8.RuneLen(quote)
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == quote:
+			return i + size
+		case r == '\\' && quote != '`':
+			i += size
+			if i < len(s) {
+				_, next := utf8.DecodeRuneInString(s[i:])
+				i += next
+			}
+			continue
+		case r == '\n' && quote != '`':
+			return i
+		}
+		i += size
+	}
+	return len(s)
+}
+
+// scan returns the byte length of the prefix of s whose runes satisfy ok.
+func scan(s string, ok func(rune) bool) int {
+	for i, r := range s {
+		if !ok(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func lineCommentAt(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.HasPrefix(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// paint writes text in color and returns its length in bytes. Colors are
+// reset at line ends so that pagers showing partial output stay readable.
+func paint(b *strings.Builder, text, color string) int {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if line != "" {
+			b.WriteString(color)
+			b.WriteString(line)
+			b.WriteString(reset)
+		}
+	}
+	return len(text)
+}
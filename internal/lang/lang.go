@@ -0,0 +1,183 @@
+// Package lang guesses a snippet's language and title from its file name
+// and content.
+package lang
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Source says how a language was detected.
+type Source string
+
+const (
+	FromModeline  Source = "modeline"
+	FromExtension Source = "file extension"
+	FromFilename  Source = "file name"
+	FromShebang   Source = "shebang"
+)
+
+type language struct {
+	name         string
+	extensions   []string
+	filenames    []string
+	interpreters []string
+	// comments are the line comment markers used to find a doc comment.
+	comments []string
+}
+
+// The highlight package uses the same names, via Canonical.
+var languages = []language{
+	{name: "go", extensions: []string{".go"}, comments: []string{"//"}},
+	{name: "python", extensions: []string{".py", ".pyw"}, interpreters: []string{"python", "python2", "python3"}, comments: []string{"#"}},
+	{name: "javascript", extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, interpreters: []string{"node", "nodejs"}, comments: []string{"//"}},
+	{name: "typescript", extensions: []string{".ts", ".mts", ".cts", ".tsx"}, interpreters: []string{"ts-node", "deno", "bun"}, comments: []string{"//"}},
+	{name: "rust", extensions: []string{".rs"}, comments: []string{"//"}},
+	{name: "java", extensions: []string{".java"}, comments: []string{"//"}},
+	{name: "kotlin", extensions: []string{".kt", ".kts"}, comments: []string{"//"}},
+	{name: "swift", extensions: []string{".swift"}, comments: []string{"//"}},
+	{name: "c", extensions: []string{".c", ".h"}, comments: []string{"//"}},
+	{name: "cpp", extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}, comments: []string{"//"}},
+	{name: "csharp", extensions: []string{".cs"}, comments: []string{"//"}},
+	{name: "ruby", extensions: []string{".rb"}, filenames: []string{"Gemfile", "Rakefile"}, interpreters: []string{"ruby"}, comments: []string{"#"}},
+	{name: "php", extensions: []string{".php"}, interpreters: []string{"php"}, comments: []string{"//", "#"}},
+	{name: "perl", extensions: []string{".pl", ".pm"}, interpreters: []string{"perl"}, comments: []string{"#"}},
+	{name: "lua", extensions: []string{".lua"}, interpreters: []string{"lua"}, comments: []string{"--"}},
+	{name: "shell", extensions: []string{".sh", ".bash", ".zsh"}, filenames: []string{".bashrc", ".zshrc", ".profile"}, interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"}, comments: []string{"#"}},
+	{name: "powershell", extensions: []string{".ps1"}, interpreters: []string{"pwsh"}, comments: []string{"#"}},
+	{name: "sql", extensions: []string{".sql"}, comments: []string{"--"}},
+	{name: "yaml", extensions: []string{".yaml", ".yml"}, comments: []string{"#"}},
+	{name: "json", extensions: []string{".json"}},
+	{name: "toml", extensions: []string{".toml"}, comments: []string{"#"}},
+	{name: "html", extensions: []string{".html", ".htm"}},
+	{name: "css", extensions: []string{".css"}},
+	{name: "markdown", extensions: []string{".md", ".markdown"}},
+	{name: "dockerfile", extensions: []string{".dockerfile"}, filenames: []string{"Dockerfile", "Containerfile"}, comments: []string{"#"}},
+	{name: "makefile", extensions: []string{".mk"}, filenames: []string{"Makefile", "GNUmakefile", "makefile"}, comments: []string{"#"}},
+}
+
+// aliases maps other common names, such as those used in modelines and
+// note metadata, to language names.
+var aliases = map[string]string{
+	"golang": "go", "py": "python", "js": "javascript", "jsx": "javascript", "ts": "typescript",
+	"tsx": "typescript", "rs": "rust", "c++": "cpp", "cc": "cpp", "h": "c", "cs": "csharp",
+	"rb": "ruby", "sh": "shell", "bash": "shell", "zsh": "shell", "yml": "yaml", "md": "markdown",
+	"make": "makefile", "ps1": "powershell",
+}
+
+// Canonical returns the language name for name or one of its aliases,
+// lower-cased. Unknown names are returned lower-cased as they are.
+func Canonical(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func byName(name string) (language, bool) {
+	name = Canonical(name)
+	for _, l := range languages {
+		if l.name == name {
+			return l, true
+		}
+	}
+	return language{}, false
+}
+
+// Detect guesses the language of content read from the file name. A vim or
+// emacs modeline wins over the file extension or name, which wins over a
+// shebang line. It returns "" if nothing matched.
+func Detect(name string, content []byte) (string, Source) {
+	if l, ok := fromModeline(content); ok {
+		return l.name, FromModeline
+	}
+	if name != "" {
+		base := filepath.Base(name)
+		ext := strings.ToLower(filepath.Ext(base))
+		for _, l := range languages {
+			for _, f := range l.filenames {
+				if base == f {
+					return l.name, FromFilename
+				}
+			}
+			for _, e := range l.extensions {
+				if ext == e {
+					return l.name, FromExtension
+				}
+			}
+		}
+	}
+	if l, ok := fromShebang(content); ok {
+		return l.name, FromShebang
+	}
+	return "", ""
+}
+
+// Extension returns the usual file extension for language, or "" if it is
+// not known.
+func Extension(language string) string {
+	l, ok := byName(language)
+	if !ok || len(l.extensions) == 0 {
+		return ""
+	}
+	return l.extensions[0]
+}
+
+var modelines = []*regexp.Regexp{
+	regexp.MustCompile(`\bvim?:.*\b(?:ft|filetype|syntax)=([\w+#-]+)`),
+	regexp.MustCompile(`-\*-.*\bmode:\s*([\w+#-]+).*-\*-`),
+	regexp.MustCompile(`-\*-\s*([\w+#-]+)\s*-\*-`),
+}
+
+// fromModeline looks for a modeline in the first and last five lines, where
+// vim and emacs look for them.
+func fromModeline(content []byte) (language, bool) {
+	lines := strings.Split(string(content), "\n")
+	candidates := lines
+	if len(lines) > 10 {
+		candidates = append(lines[:5:5], lines[len(lines)-5:]...)
+	}
+	for _, line := range candidates {
+		for _, re := range modelines {
+			if m := re.FindStringSubmatch(line); m != nil {
+				if l, ok := byName(m[1]); ok {
+					return l, true
+				}
+			}
+		}
+	}
+	return language{}, false
+}
+
+func fromShebang(content []byte) (language, bool) {
+	line, _, _ := strings.Cut(string(content), "\n")
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return language{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return language{}, false
+	}
+	interp := filepath.Base(fields[0])
+	// "#!/usr/bin/env python3" and "#!/usr/bin/env -S deno run".
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interp = filepath.Base(f)
+				break
+			}
+		}
+	}
+	for _, l := range languages {
+		for _, i := range l.interpreters {
+			if interp == i || (strings.HasPrefix(interp, i) && strings.Trim(interp[len(i):], "0123456789.") == "") {
+				return l, true
+			}
+		}
+	}
+	return language{}, false
+}
@@ -0,0 +1,160 @@
+package lang
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		want     string
+		wantFrom Source
+	}{
+		{"extension", "main.go", "package main\n", "go", FromExtension},
+		{"extension case", "Script.PY", "", "python", FromExtension},
+		{"file name", "/src/Dockerfile", "FROM alpine\n", "dockerfile", FromFilename},
+		{"shebang", "run", "#!/bin/bash\necho hi\n", "shell", FromShebang},
+		{"env shebang with version", "tool", "#!/usr/bin/env python3.12\n", "python", FromShebang},
+		{"env shebang with flags", "tool", "#!/usr/bin/env -S deno run\n", "typescript", FromShebang},
+		{"vim modeline", "notes.txt", "x = 1\n# vim: set ft=ruby:\n", "ruby", FromModeline},
+		{"emacs modeline", "notes.txt", "# -*- mode: python -*-\n", "python", FromModeline},
+		{"modeline alias", "notes.txt", "// vim: ft=golang\n", "go", FromModeline},
+		{"modeline beats extension", "a.txt.js", "// vim: ft=ts\n", "typescript", FromModeline},
+		{"extension beats shebang", "a.rb", "#!/usr/bin/env python\n", "ruby", FromExtension},
+		{"unknown", "notes.txt", "hello\n", "", ""},
+		{"no name", "", "plain\n", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, from := Detect(tt.file, []byte(tt.content))
+			if got != tt.want || from != tt.wantFrom {
+				t.Errorf("Detect = %q, %q; want %q, %q", got, from, tt.want, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := map[string]string{
+		"Go": "go", "golang": "go", " py ": "python", "TSX": "typescript", "c++": "cpp",
+		"yml": "yaml", "Elixir": "elixir",
+	}
+	for in, want := range tests {
+		if got := Canonical(in); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := map[string]string{"go": "go", "python": "py", "golang": "go", "json": "json", "cobol": ""}
+	for language, want := range tests {
+		if want != "" {
+			want = "." + want
+		}
+		if got := Extension(language); got != want {
+			t.Errorf("Extension(%q) = %q, want %q", language, got, want)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		language string
+		want     string
+	}{
+		{
+			name: "go package doc", file: "a.go", language: "go",
+			content: "//go:build linux\n\n// Package a parses flags.\npackage a\n",
+			want:    "Package a parses flags.",
+		},
+		{
+			name: "go license header", file: "a.go", language: "go",
+			content: "// Copyright 2024 Example Inc.\n// All rights reserved.\n\n// Package a parses flags.\npackage a\n",
+			want:    "Package a parses flags.",
+		},
+		{
+			name: "license and doc in one comment", file: "a.go", language: "go",
+			content: "// Copyright 2024 Example Inc.\n//\n// Parses flags.\npackage a\n",
+			want:    "Parses flags.",
+		},
+		{
+			name: "license block comment", file: "a.go", language: "go",
+			content: "/*\nCopyright 2024 Example Inc.\nLicensed under the Apache License, Version 2.0.\n*/\n\npackage a\n",
+			want:    "a.go",
+		},
+		{
+			name: "spdx line", file: "a.rs", language: "rust",
+			content: "// SPDX-License-Identifier: MIT\n\n// Retries requests.\nfn main() {}\n",
+			want:    "Retries requests.",
+		},
+		{
+			name: "python docstring on its own line", file: "a.py", language: "python",
+			content: "\"\"\"\nFetch the weather.\n\nLonger description.\n\"\"\"\nimport os\n",
+			want:    "Fetch the weather.",
+		},
+		{
+			name: "python one-line docstring", file: "a.py", language: "python",
+			content: "#!/usr/bin/env python3\n# -*- coding: utf-8 -*-\n'''Fetch the weather.'''\n",
+			want:    "Fetch the weather.",
+		},
+		{
+			name: "python comment", file: "a.py", language: "python",
+			content: "# Fetch the weather.\nimport os\n",
+			want:    "Fetch the weather.",
+		},
+		{
+			name: "c block comment", file: "a.c", language: "c",
+			content: "/*\n * Parses input.\n */\nint main(void) {}\n",
+			want:    "Parses input.",
+		},
+		{
+			name: "javadoc", file: "A.java", language: "java",
+			content: "/**\n * Parses input.\n * @author someone\n */\nclass A {}\n",
+			want:    "Parses input.",
+		},
+		{
+			name: "one-line block comment", file: "a.js", language: "javascript",
+			content: "/** Parses input. */\nexport {}\n",
+			want:    "Parses input.",
+		},
+		{
+			name: "license then block doc", file: "a.c", language: "c",
+			content: "/* Copyright (c) 2020 Someone. */\n/*\n * Parses input.\n */\n",
+			want:    "Parses input.",
+		},
+		{
+			name: "code before comments", file: "/tmp/a.go", language: "go",
+			content: "package a\n\n// Parses flags.\n",
+			want:    "a.go",
+		},
+		{
+			name: "no comment and no name", language: "go",
+			content: "package a\n",
+			want:    "",
+		},
+		{
+			name: "unknown language", file: "notes.txt",
+			content: "-- Monthly report query.\nSELECT 1;\n",
+			want:    "Monthly report query.",
+		},
+		{
+			name: "long title", file: "a.sh", language: "shell",
+			content: "# " + strings.Repeat("word ", 20) + "\n",
+			want:    strings.Repeat("word ", 15) + "word…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Title(tt.file, []byte(tt.content), tt.language); got != tt.want {
+				t.Errorf("Title = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package lang
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTitle caps the length of an inferred title in runes.
+const maxTitle = 80
+
+// Title infers a note title. A doc comment at the top of content is used
+// when there is one; otherwise the file's base name. It returns "" if
+// neither is available.
+func Title(name string, content []byte, language string) string {
+	if t := docComment(content, language); t != "" {
+		return truncate(t, maxTitle)
+	}
+	if name == "" {
+		return ""
+	}
+	return filepath.Base(name)
+}
+
+// docComment returns the first line of the first comment at the top of
+// content that is meant for readers. Shebangs, build constraints,
+// modelines and license headers are skipped.
+func docComment(content []byte, language string) string {
+	l, _ := byName(language)
+	markers := l.comments
+	if len(markers) == 0 {
+		markers = []string{"//", "#", "--"}
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || (i == 0 && strings.HasPrefix(line, "#!")) {
+			i++
+			continue
+		}
+		var block []string
+		var ok bool
+		if block, i, ok = comment(lines, i, markers); !ok {
+			// Code comes before any doc comment.
+			return ""
+		}
+		for _, para := range paragraphs(block) {
+			if isLicense(para) {
+				continue
+			}
+			for _, text := range para {
+				if !isDirective(text) {
+					return text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// comment reads the comment starting at lines[i]: a run of line comments,
+// a /* */ block or a docstring. It returns the comment's lines without
+// their markers and the index of the line after it, or false if lines[i]
+// does not start a comment.
+func comment(lines []string, i int, markers []string) ([]string, int, bool) {
+	line := strings.TrimSpace(lines[i])
+	for _, q := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(line, q) {
+			return delimited(lines, i, q, q, "")
+		}
+	}
+	if strings.HasPrefix(line, "/*") {
+		return delimited(lines, i, "/*", "*/", "*")
+	}
+
+	var block []string
+	for ; i < len(lines); i++ {
+		text, ok := cutMarker(strings.TrimSpace(lines[i]), markers)
+		if !ok {
+			break
+		}
+		block = append(block, text)
+	}
+	return block, i, len(block) > 0
+}
+
+// delimited reads a comment from open to close, dropping the delimiters and
+// any gutter (the "*" that starts each line of most C block comments).
+func delimited(lines []string, i int, open, close, gutter string) ([]string, int, bool) {
+	var block []string
+	rest := strings.TrimPrefix(strings.TrimSpace(lines[i]), open)
+	for {
+		text, closed := strings.CutSuffix(strings.TrimSpace(rest), close)
+		if !closed {
+			if before, _, found := strings.Cut(rest, close); found {
+				text, closed = before, true
+			}
+		}
+		text = strings.TrimSpace(text)
+		if gutter != "" {
+			// "/**" and " * text" both leave a gutter behind.
+			text = strings.TrimSpace(strings.TrimLeft(text, gutter))
+		}
+		block = append(block, text)
+		i++
+		if closed || i == len(lines) {
+			return block, i, true
+		}
+		rest = lines[i]
+	}
+}
+
+// paragraphs splits comment lines at blank lines.
+func paragraphs(block []string) [][]string {
+	var out [][]string
+	var cur []string
+	for _, text := range block {
+		if text == "" {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, text)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+func cutMarker(line string, markers []string) (string, bool) {
+	for _, m := range markers {
+		if rest, ok := strings.CutPrefix(line, m); ok {
+			return strings.TrimSpace(strings.TrimLeft(rest, m[:1])), true
+		}
+	}
+	return "", false
+}
+
+// isDirective reports whether comment text is meant for tools, not readers.
+func isDirective(text string) bool {
+	for _, p := range []string{"go:", "+build", "-*-", "vim:", "vi:", "eslint", "@ts-", "nolint", "coding:", "frozen_string_literal"} {
+		if strings.HasPrefix(text, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLicense reports whether a comment paragraph is a copyright or license
+// header rather than documentation.
+func isLicense(para []string) bool {
+	for _, text := range para {
+		lower := strings.ToLower(text)
+		for _, p := range []string{"copyright", "(c)", "©", "spdx-license-identifier"} {
+			if strings.HasPrefix(lower, p) {
+				return true
+			}
+		}
+		for _, s := range []string{"all rights reserved", "licensed under", "permission is hereby granted", "general public license"} {
+			if strings.Contains(lower, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
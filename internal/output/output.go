@@ -0,0 +1,160 @@
+// Package output renders command results as machine-readable documents.
+//
+// Documents are shaped by their JSON encoding, so every format uses the
+// same field names: YAML keeps the JSON field order and templates see the
+// JSON keys, e.g. {{range .}}{{.id}}{{"\n"}}{{end}}.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Kind is an output format.
+type Kind string
+
+const (
+	Text     Kind = "text"
+	JSON     Kind = "json"
+	YAML     Kind = "yaml"
+	NDJSON   Kind = "ndjson"
+	Template Kind = "template"
+)
+
+// Format is a parsed --output value.
+type Format struct {
+	Kind Kind
+	tmpl *template.Template
+}
+
+// Parse parses an --output value: text, json, yaml, ndjson, or
+// template=<go template>. An empty value means text.
+func Parse(spec string) (Format, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(spec), "=")
+	switch kind := Kind(strings.ToLower(name)); kind {
+	case "", Text, JSON, YAML, NDJSON:
+		if hasArg {
+			return Format{}, fmt.Errorf("output format %q takes no argument", name)
+		}
+		if kind == "" {
+			kind = Text
+		}
+		return Format{Kind: kind}, nil
+	case Template, "go-template":
+		if arg == "" {
+			return Format{}, errors.New("template output needs a template, e.g. --output 'template={{.id}}'")
+		}
+		tmpl, err := template.New("output").Funcs(funcs).Option("missingkey=zero").Parse(arg)
+		if err != nil {
+			return Format{}, fmt.Errorf("parse output template: %w", err)
+		}
+		return Format{Kind: Template, tmpl: tmpl}, nil
+	}
+	return Format{}, fmt.Errorf("unknown output format %q (want text, json, yaml, ndjson or template=...)", name)
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v []any) string {
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// IsText reports whether the command should print its human-readable text.
+func (f Format) IsText() bool {
+	return f.Kind == "" || f.Kind == Text
+}
+
+// Write renders v to w. NDJSON writes one line per element when v is a
+// slice and a single line otherwise.
+func (f Format) Write(w io.Writer, v any) error {
+	switch f.Kind {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return enc.Encode(v)
+		}
+		for i := range rv.Len() {
+			if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case YAML:
+		return writeYAML(w, v)
+	case Template:
+		doc, err := generic(v)
+		if err != nil {
+			return err
+		}
+		return f.tmpl.Execute(w, doc)
+	}
+	return fmt.Errorf("output format %q cannot render documents", f.Kind)
+}
+
+// generic converts v to the maps and slices of its JSON encoding.
+func generic(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	err = json.Unmarshal(b, &doc)
+	return doc, err
+}
+
+// writeYAML goes through JSON so that YAML keys and field order match the
+// JSON output.
+func writeYAML(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// blockStyle drops the flow and quoting styles that come from parsing JSON,
+// so the encoder picks its usual block layout and quotes only strings that
+// would otherwise read as another type.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Kind
+		wantErr string
+	}{
+		{spec: "", want: Text},
+		{spec: "text", want: Text},
+		{spec: " JSON ", want: JSON},
+		{spec: "yaml", want: YAML},
+		{spec: "ndjson", want: NDJSON},
+		{spec: "template={{.id}}", want: Template},
+		{spec: "go-template={{.id}}", want: Template},
+		{spec: "json=x", wantErr: "takes no argument"},
+		{spec: "template=", wantErr: "needs a template"},
+		{spec: "template={{.id", wantErr: "parse output template"},
+		{spec: "xml", wantErr: "unknown output format"},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		if f.Kind != tt.want {
+			t.Errorf("Parse(%q).Kind = %q, want %q", tt.spec, f.Kind, tt.want)
+		}
+		if f.IsText() != (tt.want == Text) {
+			t.Errorf("Parse(%q).IsText() = %v", tt.spec, f.IsText())
+		}
+	}
+}
+
+type doc struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+	Count int      `json:"count,omitempty"`
+}
+
+var docs = []doc{
+	{ID: "n1", Title: "First", Tags: []string{"a", "b"}, Count: 2},
+	{ID: "n2", Title: "123", Tags: []string{}},
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		spec string
+		v    any
+		want string
+	}{
+		{
+			spec: "json", v: docs[0],
+			want: "{\n  \"id\": \"n1\",\n  \"title\": \"First\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ],\n  \"count\": 2\n}\n",
+		},
+		{
+			spec: "ndjson", v: docs,
+			want: `{"id":"n1","title":"First","tags":["a","b"],"count":2}` + "\n" + `{"id":"n2","title":"123","tags":[]}` + "\n",
+		},
+		{
+			spec: "ndjson", v: docs[1],
+			want: `{"id":"n2","title":"123","tags":[]}` + "\n",
+		},
+		{
+			// Keys keep the JSON order, and strings that would read as
+			// another type are quoted.
+			spec: "yaml", v: docs,
+			want: "- id: n1\n  title: First\n  tags:\n    - a\n    - b\n  count: 2\n- id: n2\n  title: \"123\"\n  tags: []\n",
+		},
+		{
+			spec: `template={{range .}}{{.id}} {{join "," .tags}}{{"\n"}}{{end}}`, v: docs,
+			want: "n1 a,b\nn2 \n",
+		},
+		{
+			spec: `template={{.id}} {{.missing}} {{json .tags}}`, v: docs[0],
+			want: `n1 <no value> ["a","b"]`,
+		},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		var b strings.Builder
+		if err := f.Write(&b, tt.v); err != nil {
+			t.Errorf("%s: Write: %v", tt.spec, err)
+			continue
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: Write =\n%s\nwant\n%s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var b strings.Builder
+	if err := (Format{Kind: Text}).Write(&b, docs); err == nil {
+		t.Error("Write with text format succeeded, want an error")
+	}
+}
@@ -0,0 +1,173 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// columnGap separates table columns.
+const columnGap = "  "
+
+// Column describes one column of a Table.
+type Column struct {
+	Header string
+	// Flexible columns are truncated, widest first, when the table does not
+	// fit the terminal; others keep their natural width.
+	Flexible bool
+	// MinWidth is the narrowest a flexible column is truncated to.
+	MinWidth int
+}
+
+// Table is plain text laid out in aligned columns.
+type Table struct {
+	Columns []Column
+	Rows    [][]string
+}
+
+// Write renders the table to w. If maxWidth is positive, flexible columns
+// are truncated so that rows fit in maxWidth cells.
+func (t *Table) Write(w io.Writer, maxWidth int) error {
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = StringWidth(c.Header)
+	}
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], StringWidth(cell))
+		}
+	}
+	if maxWidth > 0 {
+		t.shrink(widths, maxWidth)
+	}
+
+	headers := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		headers[i] = c.Header
+	}
+	if err := writeRow(w, headers, widths); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		if err := writeRow(w, row, widths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Table) shrink(widths []int, maxWidth int) {
+	total := len(columnGap) * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > maxWidth {
+		widest := -1
+		for i, c := range t.Columns {
+			if c.Flexible && widths[i] > max(c.MinWidth, 1) && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+func writeRow(w io.Writer, cells []string, widths []int) error {
+	var b strings.Builder
+	for i, cell := range cells {
+		cell = Truncate(cell, widths[i])
+		b.WriteString(cell)
+		if i < len(cells)-1 {
+			b.WriteString(strings.Repeat(" ", widths[i]-StringWidth(cell)))
+			b.WriteString(columnGap)
+		}
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RuneWidth returns the number of terminal cells r occupies: 2 for East
+// Asian wide and fullwidth characters, 0 for combining marks and control
+// characters, 1 otherwise.
+func RuneWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.IsControl(r):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// StringWidth returns the number of terminal cells s occupies.
+func StringWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// Truncate shortens s to at most n cells, marking the cut with "…". It
+// never splits a rune.
+func Truncate(s string, n int) string {
+	if StringWidth(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		rw := RuneWidth(r)
+		if used+rw > n-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += rw
+	}
+	return b.String() + "…"
+}
+
+// RelativeTime describes t relative to now, e.g. "5 minutes ago". Times
+// more than a month away are shown as a date.
+func RelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	suffix := "ago"
+	if d < 0 {
+		d, suffix = -d, "from now"
+	}
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	default:
+		return t.Local().Format(time.DateOnly)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s %s", n, unit, suffix)
+}
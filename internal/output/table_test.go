@@ -0,0 +1,129 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStringWidth(t *testing.T) {
+	tests := map[string]int{
+		"":        0,
+		"abc":     3,
+		"日本語":     6,
+		"ｱｲｳ":     3, // halfwidth katakana
+		"e\u0301": 1, // combining acute accent
+		"a\tb":    2,
+		"…":       1,
+	}
+	for s, want := range tests {
+		if got := StringWidth(s); got != want {
+			t.Errorf("StringWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 10, "hello"},
+		{"hello", 4, "hel…"},
+		{"hello", 1, "…"},
+		{"hello", 0, ""},
+		{"日本語", 4, "日…"},
+		{"日本語", 5, "日本…"},
+		{"日本語", 6, "日本語"},
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.n)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+		if w := StringWidth(got); w > max(tt.n, 0) {
+			t.Errorf("Truncate(%q, %d) is %d cells wide", tt.s, tt.n, w)
+		}
+	}
+}
+
+func TestTableWrite(t *testing.T) {
+	table := Table{
+		Columns: []Column{
+			{Header: "ID"},
+			{Header: "TITLE", Flexible: true, MinWidth: 5},
+			{Header: "TAGS", Flexible: true, MinWidth: 4},
+		},
+		Rows: [][]string{
+			{"n1", "A fairly long title", "go,http"},
+			{"n22", "日本語のタイトル", ""},
+		},
+	}
+	tests := []struct {
+		name     string
+		maxWidth int
+		want     string
+	}{
+		{
+			name: "unlimited", maxWidth: 0,
+			want: "ID   TITLE                TAGS\n" +
+				"n1   A fairly long title  go,http\n" +
+				"n22  日本語のタイトル     \n",
+		},
+		{
+			name: "fits", maxWidth: 80,
+			want: "ID   TITLE                TAGS\n" +
+				"n1   A fairly long title  go,http\n" +
+				"n22  日本語のタイトル     \n",
+		},
+		{
+			// The widest flexible column shrinks first.
+			name: "shrinks title", maxWidth: 24,
+			want: "ID   TITLE       TAGS\n" +
+				"n1   A fairly …  go,http\n" +
+				"n22  日本語の…   \n",
+		},
+		{
+			// Flexible columns stop at their minimum width.
+			name: "too narrow", maxWidth: 10,
+			want: "ID   TITLE  TAGS\n" +
+				"n1   A fa…  go,…\n" +
+				"n22  日本…  \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := table.Write(&b, tt.maxWidth); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Write =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Time{}, ""},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-5 * time.Minute), "5 minutes ago"},
+		{now.Add(-3 * time.Hour), "3 hours ago"},
+		{now.Add(-24 * time.Hour), "1 day ago"},
+		{now.Add(2 * time.Hour), "2 hours from now"},
+		{now.AddDate(0, -2, 0), now.AddDate(0, -2, 0).Local().Format(time.DateOnly)},
+	}
+	for _, tt := range tests {
+		if got := RelativeTime(tt.t, now); got != tt.want {
+			t.Errorf("RelativeTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
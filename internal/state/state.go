@@ -1,16 +1,64 @@
 package state
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 type FileMapping struct {
 	NoteID string `json:"note_id"`
+	// ContentHash is the hash of the content both sides had at SyncedAt.
+	ContentHash string `json:"content_hash,omitempty"`
+	// RemoteUpdatedAt and RemoteRevision identify the note version at SyncedAt.
+	RemoteUpdatedAt time.Time `json:"remote_updated_at,omitzero"`
+	RemoteRevision  string    `json:"remote_revision,omitempty"`
+	// SyncedAt is when the file and the note were last known to match.
+	SyncedAt time.Time `json:"synced_at,omitzero"`
+}
+
+// SyncedMapping records that content matches the given version of a note.
+// remoteUpdatedAt and revision may be zero when the server did not say.
+func SyncedMapping(noteID string, content []byte, remoteUpdatedAt time.Time, revision string) FileMapping {
+	return FileMapping{
+		NoteID:          noteID,
+		ContentHash:     HashContent(content),
+		RemoteUpdatedAt: remoteUpdatedAt,
+		RemoteRevision:  revision,
+		SyncedAt:        time.Now(),
+	}
+}
+
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LocalChanged reports whether the local file differs from the last synced
+// content. Mappings without a hash fall back to the file's mtime.
+func (m FileMapping) LocalChanged(content []byte, modTime time.Time) bool {
+	if m.ContentHash != "" {
+		return HashContent(content) != m.ContentHash
+	}
+	return m.SyncedAt.IsZero() || modTime.After(m.SyncedAt)
+}
+
+// RemoteChanged reports whether the note moved past the last synced version.
+func (m FileMapping) RemoteChanged(updatedAt time.Time, revision string) bool {
+	if m.RemoteRevision != "" && revision != "" {
+		return revision != m.RemoteRevision
+	}
+	if !m.RemoteUpdatedAt.IsZero() {
+		return !updatedAt.Equal(m.RemoteUpdatedAt)
+	}
+	return m.SyncedAt.IsZero() || updatedAt.After(m.SyncedAt)
 }
 
 type Scope string
@@ -24,16 +72,21 @@ type Context struct {
 	Name       string `json:"name"`
 	Collection string `json:"collection"`
 	Folder     string `json:"folder"`
+	// Profile pins the context to a config profile; empty uses the active one.
+	Profile string `json:"profile,omitempty"`
 }
 
 type State struct {
-	Contexts         map[string]Context                `json:"contexts"`
-	CurrentContext   string                            `json:"current_context"`
-	CurrentScope     Scope                             `json:"current_scope,omitempty"`
-	CurrentNoteID    string                            `json:"current_note,omitempty"`
-	CurrentNoteTitle string                            `json:"current_note_title,omitempty"`
-	Files            map[string]map[string]FileMapping `json:"files"`
-	path             string
+	Contexts         map[string]Context `json:"contexts"`
+	CurrentContext   string             `json:"current_context"`
+	CurrentScope     Scope              `json:"current_scope,omitempty"`
+	CurrentNoteID    string             `json:"current_note,omitempty"`
+	CurrentNoteTitle string             `json:"current_note_title,omitempty"`
+	// CurrentNoteUpdatedAt is the note's updated_at when note scope was
+	// entered or the note last updated, used as an edit precondition.
+	CurrentNoteUpdatedAt time.Time                         `json:"current_note_updated_at,omitzero"`
+	Files                map[string]map[string]FileMapping `json:"files"`
+	path                 string
 }
 
 func Load(root string) (*State, error) {
@@ -67,6 +120,7 @@ func Load(root string) (*State, error) {
 	if st.CurrentScope != ScopeNote {
 		st.CurrentNoteID = ""
 		st.CurrentNoteTitle = ""
+		st.CurrentNoteUpdatedAt = time.Time{}
 	}
 	st.path = path
 	return &st, nil
@@ -87,10 +141,77 @@ func (s *State) Save() error {
 	if err := os.WriteFile(s.path, data, 0o600); err != nil {
 		return fmt.Errorf("write state: %w", err)
 	}
+	return s.pruneBases()
+}
+
+func (s *State) baseDir() string {
+	return filepath.Join(filepath.Dir(s.path), "base")
+}
+
+func (s *State) basePath(hash string) (string, bool) {
+	name, ok := strings.CutPrefix(hash, "sha256:")
+	if !ok || name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", false
+	}
+	return filepath.Join(s.baseDir(), name), true
+}
+
+// SaveBase stores content as the common ancestor for later three-way
+// merges. It is addressed by HashContent and removed by Save once no
+// mapping refers to it.
+func (s *State) SaveBase(content []byte) error {
+	path, _ := s.basePath(HashContent(content))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create base dir: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("write base: %w", err)
+	}
 	return nil
 }
 
-func (s *State) SetContext(name, collectionID, folderID string) {
+// Base returns the content last synced for m, or os.ErrNotExist if it was
+// not recorded.
+func (s *State) Base(m FileMapping) ([]byte, error) {
+	path, ok := s.basePath(m.ContentHash)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if HashContent(data) != m.ContentHash {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (s *State) pruneBases() error {
+	entries, err := os.ReadDir(s.baseDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read base dir: %w", err)
+	}
+	used := make(map[string]bool)
+	for _, files := range s.Files {
+		for _, m := range files {
+			used[m.ContentHash] = true
+		}
+	}
+	for _, e := range entries {
+		if !used["sha256:"+e.Name()] {
+			if err := os.Remove(filepath.Join(s.baseDir(), e.Name())); err != nil {
+				return fmt.Errorf("prune base: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *State) SetContext(name, collectionID, folderID, profile string) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		name = "default"
@@ -99,6 +220,7 @@ func (s *State) SetContext(name, collectionID, folderID string) {
 		Name:       name,
 		Collection: collectionID,
 		Folder:     folderID,
+		Profile:    strings.TrimSpace(profile),
 	}
 	if s.CurrentContext == "" {
 		s.CurrentContext = name
@@ -125,7 +247,7 @@ func (s *State) Current() (Context, error) {
 	return ctx, nil
 }
 
-func (s *State) SetFileMapping(ctxName, relativePath, noteID string) {
+func (s *State) SetFileMapping(ctxName, relativePath string, m FileMapping) {
 	if s.Files == nil {
 		s.Files = make(map[string]map[string]FileMapping)
 	}
@@ -133,7 +255,7 @@ func (s *State) SetFileMapping(ctxName, relativePath, noteID string) {
 		s.Files[ctxName] = make(map[string]FileMapping)
 	}
 	rel := strings.ReplaceAll(relativePath, "\\", "/")
-	s.Files[ctxName][rel] = FileMapping{NoteID: noteID}
+	s.Files[ctxName][rel] = m
 }
 
 func (s *State) GetFileMapping(ctxName, relativePath string) (FileMapping, bool) {
@@ -149,13 +271,52 @@ func (s *State) GetFileMapping(ctxName, relativePath string) (FileMapping, bool)
 	return m, ok
 }
 
+// FileForNote returns the relative path mapped to noteID in a context. If
+// several files map to the note, the first in lexical order wins.
+func (s *State) FileForNote(ctxName, noteID string) (string, bool) {
+	found := ""
+	for rel, m := range s.Files[ctxName] {
+		if m.NoteID == noteID && (found == "" || rel < found) {
+			found = rel
+		}
+	}
+	return found, found != ""
+}
+
+// RemoveNoteMappings drops every mapping to noteID in all contexts and
+// returns how many were removed.
+func (s *State) RemoveNoteMappings(noteID string) int {
+	n := 0
+	for _, files := range s.Files {
+		for rel, m := range files {
+			if m.NoteID == noteID {
+				delete(files, rel)
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// FileMappings returns the relative paths mapped in a context, sorted.
+func (s *State) FileMappings(ctxName string) []string {
+	files := s.Files[ctxName]
+	paths := make([]string, 0, len(files))
+	for rel := range files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 func (s *State) EnterFolderScope() {
 	s.CurrentScope = ScopeFolder
 	s.CurrentNoteID = ""
 	s.CurrentNoteTitle = ""
+	s.CurrentNoteUpdatedAt = time.Time{}
 }
 
-func (s *State) EnterNoteScope(noteID, title string) error {
+func (s *State) EnterNoteScope(noteID, title string, updatedAt time.Time) error {
 	noteID = strings.TrimSpace(noteID)
 	if noteID == "" {
 		return errors.New("note id is required")
@@ -163,6 +324,7 @@ func (s *State) EnterNoteScope(noteID, title string) error {
 	s.CurrentScope = ScopeNote
 	s.CurrentNoteID = noteID
 	s.CurrentNoteTitle = strings.TrimSpace(title)
+	s.CurrentNoteUpdatedAt = updatedAt
 	return nil
 }
 
@@ -0,0 +1,172 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocalChanged(t *testing.T) {
+	synced := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	content := []byte("package a\n")
+	tests := []struct {
+		name    string
+		mapping FileMapping
+		content []byte
+		modTime time.Time
+		want    bool
+	}{
+		{"same content", FileMapping{ContentHash: HashContent(content), SyncedAt: synced}, content, synced.Add(time.Hour), false},
+		{"edited content", FileMapping{ContentHash: HashContent(content), SyncedAt: synced}, []byte("package b\n"), synced.Add(-time.Hour), true},
+		// Legacy mappings from before content hashes fall back to mtime.
+		{"legacy, untouched", FileMapping{SyncedAt: synced}, content, synced.Add(-time.Second), false},
+		{"legacy, touched", FileMapping{SyncedAt: synced}, content, synced.Add(time.Second), true},
+		{"legacy without sync time", FileMapping{}, content, synced, true},
+	}
+	for _, tt := range tests {
+		if got := tt.mapping.LocalChanged(tt.content, tt.modTime); got != tt.want {
+			t.Errorf("%s: LocalChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemoteChanged(t *testing.T) {
+	synced := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	updated := synced.Add(-time.Minute)
+	tests := []struct {
+		name      string
+		mapping   FileMapping
+		updatedAt time.Time
+		revision  string
+		want      bool
+	}{
+		{"same revision", FileMapping{RemoteRevision: `"r1"`, RemoteUpdatedAt: updated}, updated, `"r1"`, false},
+		{"new revision", FileMapping{RemoteRevision: `"r1"`, RemoteUpdatedAt: updated}, updated, `"r2"`, true},
+		// A revision wins over timestamps, which may be coarse.
+		{"same revision, new time", FileMapping{RemoteRevision: `"r1"`, RemoteUpdatedAt: updated}, synced, `"r1"`, false},
+		{"server sends no revision", FileMapping{RemoteRevision: `"r1"`, RemoteUpdatedAt: updated}, updated, "", false},
+		{"same updated_at", FileMapping{RemoteUpdatedAt: updated}, updated, `"r1"`, false},
+		{"new updated_at", FileMapping{RemoteUpdatedAt: updated}, updated.Add(time.Second), "", true},
+		// Legacy mappings compare with the time of the last sync.
+		{"legacy, older note", FileMapping{SyncedAt: synced}, updated, "", false},
+		{"legacy, newer note", FileMapping{SyncedAt: synced}, synced.Add(time.Second), "", true},
+		{"legacy without sync time", FileMapping{}, updated, "", true},
+	}
+	for _, tt := range tests {
+		if got := tt.mapping.RemoteChanged(tt.updatedAt, tt.revision); got != tt.want {
+			t.Errorf("%s: RemoteChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadLegacyMappings(t *testing.T) {
+	root := t.TempDir()
+	legacy := `{
+  "contexts": {"default": {"name": "default", "collection": "c1", "folder": "f1"}},
+  "current_context": "default",
+  "files": {"default": {"a.go": {"note_id": "n1"}, "b.go": {"note_id": "n2", "synced_at": "2026-03-01T12:00:00Z"}}}
+}`
+	if err := os.MkdirAll(filepath.Join(root, ".codestash"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".codestash", "state.json"), []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Scope() != ScopeFolder {
+		t.Errorf("scope = %q, want folder", st.Scope())
+	}
+	m, ok := st.GetFileMapping("default", "a.go")
+	if !ok || m.NoteID != "n1" || m.ContentHash != "" {
+		t.Fatalf("a.go maps to %+v, %v", m, ok)
+	}
+	// Without a hash or sync time, both sides count as changed, so the
+	// file is treated as a conflict rather than silently overwritten.
+	if !m.LocalChanged([]byte("x"), time.Now()) || !m.RemoteChanged(time.Now(), "") {
+		t.Error("a mapping without sync data should count as changed on both sides")
+	}
+	if _, err := st.Base(m); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Base of a legacy mapping = %v, want os.ErrNotExist", err)
+	}
+	if got := st.FileMappings("default"); len(got) != 2 || got[0] != "a.go" || got[1] != "b.go" {
+		t.Errorf("FileMappings = %v, want [a.go b.go]", got)
+	}
+}
+
+func TestBasePruning(t *testing.T) {
+	st, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, v2, other := []byte("v1\n"), []byte("v2\n"), []byte("other\n")
+	for _, content := range [][]byte{v1, v2, other} {
+		if err := st.SaveBase(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.SetFileMapping("default", "a.go", SyncedMapping("n1", v1, time.Time{}, ""))
+	st.SetFileMapping("work", `dir\b.go`, SyncedMapping("n2", other, time.Time{}, ""))
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 was never mapped, so Save prunes it; the others stay.
+	for _, tt := range []struct {
+		name    string
+		content []byte
+		want    bool
+	}{{"v1", v1, true}, {"v2", v2, false}, {"other", other, true}} {
+		_, err := st.Base(FileMapping{ContentHash: HashContent(tt.content)})
+		if got := err == nil; got != tt.want {
+			t.Errorf("base %s kept = %v (%v), want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	// Moving a.go on to v2 releases v1; deleting n2's mapping releases other.
+	if err := st.SaveBase(v2); err != nil {
+		t.Fatal(err)
+	}
+	st.SetFileMapping("default", "a.go", SyncedMapping("n1", v2, time.Time{}, ""))
+	if n := st.RemoveNoteMappings("n2"); n != 1 {
+		t.Errorf("RemoveNoteMappings removed %d, want 1", n)
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(st.baseDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || "sha256:"+entries[0].Name() != HashContent(v2) {
+		t.Errorf("base dir holds %v, want only v2", entries)
+	}
+	if got, err := st.Base(FileMapping{ContentHash: HashContent(v2)}); err != nil || string(got) != "v2\n" {
+		t.Errorf("Base(v2) = %q, %v", got, err)
+	}
+}
+
+func TestBaseRejectsBadHashes(t *testing.T) {
+	st, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveBase([]byte("v1\n")); err != nil {
+		t.Fatal(err)
+	}
+	// A tampered base no longer matches its hash.
+	path, _ := st.basePath(HashContent([]byte("v1\n")))
+	if err := os.WriteFile(path, []byte("tampered\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{HashContent([]byte("v1\n")), "", "md5:abc", "sha256:../../state.json"} {
+		if _, err := st.Base(FileMapping{ContentHash: hash}); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Base(%q) = %v, want os.ErrNotExist", hash, err)
+		}
+	}
+}